@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/akatranlp/concur/internal/cmd"
+	"github.com/akatranlp/concur/internal/config"
+	"github.com/akatranlp/concur/internal/control"
+	"github.com/akatranlp/concur/internal/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var socketPath string
+var client *control.Client
+
+var ctlCmd = &cobra.Command{
+	Use:   "ctl",
+	Short: "Control a running concur through its control socket",
+	Long: `Control a running concur through its control socket.
+concur has to run with control.enabled (--control). Without --socket the
+socket is found through the config file, like concur itself does.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	PersistentPreRunE: func(*cobra.Command, []string) error {
+		path := socketPath
+		if path == "" {
+			var err error
+			if path, err = defaultSocketPath(); err != nil {
+				return err
+			}
+		}
+		client = control.NewClient(path)
+		return nil
+	},
+}
+
+// defaultSocketPath returns the socket of the config file, if there is one,
+// or the default socket of the working directory.
+func defaultSocketPath() (string, error) {
+	var notFound viper.ConfigFileNotFoundError
+	if err := readConfigFile(); err != nil && !errors.As(err, &notFound) {
+		return "", err
+	}
+	return config.ControlConfig{Socket: viper.GetString("control.socket")}.SocketPath()
+}
+
+var ctlPsCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "List the commands with their state, pid, exit code and health",
+	Args:  cobra.NoArgs,
+	RunE: func(ccmd *cobra.Command, _ []string) error {
+		statuses, err := client.Commands(ccmd.Context())
+		if err != nil {
+			return err
+		}
+		return printCommands(statuses)
+	},
+}
+
+// printCommands prints the state of the commands as a table.
+func printCommands(statuses []control.CommandStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tNAME\tSTATE\tPID\tEXIT\tHEALTH\tUPTIME")
+	for _, status := range statuses {
+		pid, exit, health, uptime := "-", "-", "-", "-"
+		if status.Pid != 0 {
+			pid = fmt.Sprint(status.Pid)
+		}
+		if status.ExitCode != nil {
+			exit = fmt.Sprint(*status.ExitCode)
+		}
+		if status.Health != "" {
+			health = string(status.Health)
+		}
+		if status.State == cmd.StateRunning && status.StartedAt != nil {
+			uptime = time.Since(*status.StartedAt).Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", status.Index, status.DisplayName(), status.State, pid, exit, health, uptime)
+	}
+	return w.Flush()
+}
+
+// newActionCmd creates a subcommand that runs the action for every given command.
+func newActionCmd(use, short, done string, action func(*control.Client, context.Context, string) (control.CommandStatus, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " <name|index>...",
+		Short: short,
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(ccmd *cobra.Command, args []string) error {
+			for _, target := range args {
+				status, err := action(client, ccmd.Context(), target)
+				if err != nil {
+					return fmt.Errorf("%s: %w", target, err)
+				}
+				fmt.Printf("%s %s\n", done, status.DisplayName())
+			}
+			return nil
+		},
+	}
+}
+
+var ctlSignalCmd = &cobra.Command{
+	Use:   "signal <name|index> <signal>",
+	Short: "Send a signal, like SIGHUP, to a command",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(ccmd *cobra.Command, args []string) error {
+		status, err := client.Signal(ccmd.Context(), args[0], args[1])
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		fmt.Printf("sent %s to %s\n", strings.ToUpper(args[1]), status.DisplayName())
+		return nil
+	},
+}
+
+var logsFollow bool
+var logsLines int
+
+var ctlLogsCmd = &cobra.Command{
+	Use:   "logs [name|index]",
+	Short: "Show the recent output of a command or of all commands",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(ccmd *cobra.Command, args []string) error {
+		var target string
+		if len(args) > 0 {
+			target = args[0]
+		}
+
+		// Lines of all commands are marked with the name of their command.
+		var names []string
+		if target == "" {
+			statuses, err := client.Commands(ccmd.Context())
+			if err != nil {
+				return err
+			}
+			for _, status := range statuses {
+				names = append(names, status.DisplayName())
+			}
+		}
+
+		return client.Logs(ccmd.Context(), target, logsLines, logsFollow, func(line control.LogLine) {
+			out := os.Stdout
+			if line.Stream == logger.StreamStderr {
+				out = os.Stderr
+			}
+			if line.Index < len(names) {
+				fmt.Fprintf(out, "[%s] %s\n", names[line.Index], line.Text)
+				return
+			}
+			fmt.Fprintln(out, line.Text)
+		})
+	},
+}
+
+var ctlHealthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Show the results of the status checks and the health checks of the commands",
+	Args:  cobra.NoArgs,
+	RunE: func(ccmd *cobra.Command, _ []string) error {
+		checks, err := client.Health(ccmd.Context())
+		if err != nil {
+			return err
+		}
+		for _, check := range checks {
+			name := check.Name
+			if check.Check != nil {
+				name = fmt.Sprintf("check %d", *check.Check)
+			} else if name == "" {
+				name = fmt.Sprintf("command %d", *check.Index)
+			}
+			fmt.Printf("%s: %s\n", name, check.Status)
+			for _, message := range check.Messages {
+				fmt.Printf("  %s\n", message)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ctlCmd)
+	ctlCmd.PersistentFlags().StringVar(&socketPath, "socket", "", "Path of the control socket (default: control.socket or the default socket of the config)")
+
+	ctlCmd.AddCommand(ctlPsCmd)
+	ctlCmd.AddCommand(newActionCmd("restart", "Restart commands", "restarted", (*control.Client).Restart))
+	ctlCmd.AddCommand(newActionCmd("stop", "Stop commands until they are started again", "stopped", (*control.Client).Stop))
+	ctlCmd.AddCommand(newActionCmd("start", "Start stopped commands", "started", (*control.Client).Start))
+	ctlCmd.AddCommand(ctlSignalCmd)
+	ctlCmd.AddCommand(ctlLogsCmd)
+	ctlCmd.AddCommand(ctlHealthCmd)
+
+	ctlLogsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep showing new lines")
+	ctlLogsCmd.Flags().IntVarP(&logsLines, "lines", "n", 100, "Number of recent lines to show")
+}
@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/akatranlp/concur/internal/config"
+	"github.com/akatranlp/concur/internal/control"
+	"github.com/akatranlp/concur/internal/daemon"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var detach bool
+var daemonMode bool
+var attachLines int
+
+// daemonState is the state file of concur running in the background, nil
+// otherwise.
+var daemonState *daemon.StateFile
+
+var upCmd = &cobra.Command{
+	Use:   "up [commands...]",
+	Short: "Run the commands like concur itself, with -d in the background",
+	Long: `Run the commands like concur itself, with -d in the background.
+In the background concur keeps running when the terminal is closed. Its
+output is written to a file, which concur attach follows, and concur down
+shuts the commands down and runs runAfter. The control socket is always
+enabled, so concur ctl works as well.`,
+	Args:          cobra.ArbitraryArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(ccmd *cobra.Command, args []string) error {
+		if detach {
+			return startDaemon(ccmd, args)
+		}
+		return rootCmd.RunE(ccmd, args)
+	},
+}
+
+var attachCmd = &cobra.Command{
+	Use:   "attach",
+	Short: "Follow the output of concur running in the background, Ctrl+C detaches",
+	Args:  cobra.NoArgs,
+	RunE: func(ccmd *cobra.Command, _ []string) error {
+		state, err := readDaemonState()
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(state.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := daemon.SeekLastLines(f, attachLines); err != nil {
+			return err
+		}
+		return daemon.Follow(ccmd.Context(), f, state.Pid, os.Stdout)
+	},
+}
+
+var downCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Shut down concur running in the background and run runAfter",
+	Args:  cobra.NoArgs,
+	RunE: func(ccmd *cobra.Command, _ []string) error {
+		state, err := readDaemonState()
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(state.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ccmd.Context(), 5*time.Second)
+		err = control.NewClient(state.Socket).Shutdown(ctx)
+		cancel()
+		if err != nil {
+			// The socket is not there yet while runBefore runs.
+			if err := daemon.Terminate(state.Pid); err != nil {
+				return err
+			}
+		}
+		// Shows the shutdown and runAfter until concur exited.
+		return daemon.Follow(ccmd.Context(), f, state.Pid, os.Stdout)
+	},
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of concur running in the background",
+	Args:  cobra.NoArgs,
+	RunE: func(ccmd *cobra.Command, _ []string) error {
+		state, err := readDaemonState()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("concur is running in the background (pid %d, up %s)\n", state.Pid, time.Since(state.StartedAt).Round(time.Second))
+		if state.Config != "" {
+			fmt.Printf("config: %s\n", state.Config)
+		}
+		fmt.Printf("socket: %s\n", state.Socket)
+		fmt.Printf("output: %s\n\n", state.Output)
+
+		ctx, cancel := context.WithTimeout(ccmd.Context(), 5*time.Second)
+		defer cancel()
+		if statuses, err := control.NewClient(state.Socket).Commands(ctx); err == nil {
+			if err := printCommands(statuses); err != nil {
+				return err
+			}
+		} else if len(state.Commands) == 0 {
+			fmt.Println("the commands are not started yet")
+		} else {
+			// The control socket is not there anymore while runAfter runs.
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "INDEX\tNAME\tLAST PID")
+			for _, command := range state.Commands {
+				fmt.Fprintf(w, "%d\t%s\t%d\n", command.Index, commandName(command), command.Pid)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		var hasLogs bool
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, command := range state.Commands {
+			if command.LogFile == "" {
+				continue
+			}
+			if !hasLogs {
+				fmt.Fprintln(w, "\nNAME\tLOG FILE")
+				hasLogs = true
+			}
+			fmt.Fprintf(w, "%s\t%s\n", commandName(command), command.LogFile)
+		}
+		return w.Flush()
+	},
+}
+
+func commandName(command daemon.CommandState) string {
+	if command.Name != "" {
+		return command.Name
+	}
+	return command.Command
+}
+
+// readDaemonState reads the state of concur running in the background, which
+// is found through the config file like the control socket.
+func readDaemonState() (daemon.State, error) {
+	socket, err := defaultSocketPath()
+	if err != nil {
+		return daemon.State{}, err
+	}
+	statePath, _ := daemon.Paths(socket)
+	return daemon.ReadState(statePath)
+}
+
+// startDaemon starts concur again in the background with the same flags and
+// waits until it wrote its state file.
+func startDaemon(ccmd *cobra.Command, args []string) error {
+	viper.Set("control.enabled", true)
+	cfg, err := config.ParseConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.TUI || cfg.Keyboard || cfg.HandleInput {
+		return errors.New("tui, keyboard and handleInput need a terminal and are not supported in the background")
+	}
+
+	socket, err := cfg.Control.SocketPath()
+	if err != nil {
+		return err
+	}
+	statePath, outputPath := daemon.Paths(socket)
+	if state, err := daemon.ReadState(statePath); err == nil {
+		return fmt.Errorf("concur is already running in the background (pid %d), stop it with concur down", state.Pid)
+	} else if !errors.Is(err, daemon.ErrNotRunning) {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o700); err != nil {
+		return err
+	}
+	output, err := os.OpenFile(outputPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	child := exec.Command(exe, daemonArgs(ccmd, args)...)
+	child.Stdout = output
+	child.Stderr = output
+	child.Env = os.Environ()
+	// The output is meant to be attached to from the terminal.
+	if cfg.ColorMode.Enabled(os.Stdout) {
+		child.Env = append(child.Env, "FORCE_COLOR=1")
+	}
+	daemon.Detach(child)
+	if err := child.Start(); err != nil {
+		return err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		_ = child.Wait()
+		close(exited)
+	}()
+
+	timeout := time.After(5 * time.Second)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exited:
+			if data, err := os.ReadFile(outputPath); err == nil {
+				os.Stderr.Write(data)
+			}
+			return errors.New("concur exited right away")
+		case <-timeout:
+			return fmt.Errorf("concur did not start in time, see %s", outputPath)
+		case <-ticker.C:
+		}
+		if state, err := daemon.ReadState(statePath); err == nil {
+			fmt.Printf("concur is running in the background (pid %d)\n", state.Pid)
+			fmt.Printf("output: %s\n", state.Output)
+			fmt.Println("follow it with concur attach, stop it with concur down")
+			return nil
+		}
+	}
+}
+
+// daemonArgs returns the arguments to run the command again in the background.
+func daemonArgs(ccmd *cobra.Command, args []string) []string {
+	daemonArgs := []string{"up", "--daemon"}
+	ccmd.Flags().Visit(func(f *pflag.Flag) {
+		if f.Name == "detach" {
+			return
+		}
+		if values, ok := f.Value.(pflag.SliceValue); ok {
+			for _, value := range values.GetSlice() {
+				daemonArgs = append(daemonArgs, "--"+f.Name+"="+value)
+			}
+			return
+		}
+		daemonArgs = append(daemonArgs, "--"+f.Name+"="+f.Value.String())
+	})
+	return append(append(daemonArgs, "--"), args...)
+}
+
+// newDaemonState writes the state file of concur running in the background.
+func newDaemonState(cfg *config.Config) (*daemon.StateFile, error) {
+	socket, err := cfg.Control.SocketPath()
+	if err != nil {
+		return nil, err
+	}
+	statePath, outputPath := daemon.Paths(socket)
+
+	configFile := viper.ConfigFileUsed()
+	if configFile != "" {
+		if abs, err := filepath.Abs(configFile); err == nil {
+			configFile = abs
+		}
+	}
+	state := daemon.NewStateFile(statePath, daemon.State{
+		Pid:       os.Getpid(),
+		StartedAt: time.Now(),
+		Config:    configFile,
+		Socket:    socket,
+		Output:    outputPath,
+	})
+	return state, state.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(upCmd)
+	rootCmd.AddCommand(attachCmd)
+	rootCmd.AddCommand(downCmd)
+	rootCmd.AddCommand(statusCmd)
+
+	upCmd.Flags().BoolVarP(&detach, "detach", "d", false, "Run in the background")
+	upCmd.Flags().BoolVar(&daemonMode, "daemon", false, "Run as the background process started by -d")
+	upCmd.Flags().MarkHidden("daemon")
+
+	attachCmd.Flags().IntVarP(&attachLines, "lines", "n", 100, "Number of recent lines to show")
+}
@@ -22,20 +22,28 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"sync"
-	"time"
 
 	"github.com/akatranlp/concur/internal/cmd"
 	"github.com/akatranlp/concur/internal/config"
+	"github.com/akatranlp/concur/internal/control"
+	"github.com/akatranlp/concur/internal/daemon"
 	healthcheck "github.com/akatranlp/concur/internal/health_check"
+	"github.com/akatranlp/concur/internal/input"
+	"github.com/akatranlp/concur/internal/keyboard"
 	"github.com/akatranlp/concur/internal/logger"
 	"github.com/akatranlp/concur/internal/prefix"
+	"github.com/akatranlp/concur/internal/tui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 var commandNames []string
@@ -72,18 +80,7 @@ var rootCmd = &cobra.Command{
 	Args:    cobra.ArbitraryArgs,
 	PreRunE: func(_ *cobra.Command, args []string) error {
 		if len(args) == 0 {
-			if cfgFile != "" {
-				viper.SetConfigFile(cfgFile)
-			} else {
-				cwd, err := os.Getwd()
-				cobra.CheckErr(err)
-
-				viper.AddConfigPath(cwd)
-				viper.SetConfigType("yaml")
-				viper.SetConfigName(".concur")
-			}
-
-			return viper.ReadInConfig()
+			return readConfigFile()
 		}
 
 		runCfgs := make([]config.RunCommandConfig, len(args))
@@ -123,28 +120,65 @@ var rootCmd = &cobra.Command{
 	SilenceUsage:  true,
 	SilenceErrors: true,
 	RunE: func(ccmd *cobra.Command, args []string) error {
+		if daemonMode {
+			viper.Set("control.enabled", true)
+		}
 		cfg, err := config.ParseConfig()
 		if err != nil {
 			return err
 		}
+		if daemonMode {
+			state, err := newDaemonState(cfg)
+			if err != nil {
+				return err
+			}
+			defer state.Remove()
+			daemonState = state
+		}
 
 		if cfg.Debug {
 			cfg.PrintDebug()
 		}
+		config.SetColorsEnabled(cfg.ColorMode.Enabled(os.Stdout))
+		if cfg.TUI && !term.IsTerminal(int(os.Stdout.Fd())) {
+			return errors.New("tui needs a terminal")
+		}
+		if cfg.Keyboard && !term.IsTerminal(int(os.Stdin.Fd())) {
+			return errors.New("keyboard needs a terminal")
+		}
 
 		ctx := ccmd.Context()
 
+		// In json mode stdout only contains the records of the concurrently
+		// run commands, everything else is written to stderr.
+		var out io.Writer = os.Stdout
+		if cfg.Output == config.OutputFormatJSON {
+			out = os.Stderr
+		}
+
 		if len(cfg.RunBefore.Commands) > 0 {
-			fmt.Println("\033[1m[RunBefore]\033[0m")
-			for _, command := range cfg.RunBefore.Commands {
-				sh := cmd.NewCommand(ctx, cfg.KillSignal.Sys(), command.RunCommandConfig)
-				if err := sh.RunRaw(); err != nil {
+			printHeader(out, "RunBefore")
+			steps := make([]pipelineStep, len(cfg.RunBefore.Commands))
+			for i, command := range cfg.RunBefore.Commands {
+				env, err := cfg.CommandEnviron(command.RunCommandConfig)
+				if err != nil {
 					return err
 				}
+				steps[i] = pipelineStep{command.RunCommandConfig, command.PipelineConfig, env}
+			}
+			if err := runPipeline(ctx, steps, out); err != nil {
+				return err
 			}
 		}
 
-		fmt.Println("\033[1m[Concurrently]\033[0m")
+		if cfg.WaitFor.Enabled() {
+			printHeader(out, "WaitFor")
+			if err := waitFor(ctx, cfg, out); err != nil {
+				return err
+			}
+		}
+
+		printHeader(out, "Concurrently")
 		if cfg.Raw {
 			err = ExecuteRawMode(ctx, cfg)
 		} else {
@@ -152,22 +186,96 @@ var rootCmd = &cobra.Command{
 		}
 
 		if len(cfg.RunAfter.Commands) > 0 {
-			fmt.Println("\033[1m[RunAfter]\033[0m")
-			for _, command := range cfg.RunAfter.Commands {
-				sh := cmd.NewCommand(context.Background(), cfg.KillSignal.Sys(), command.RunCommandConfig)
-				if err := sh.RunRaw(); err != nil {
+			printHeader(out, "RunAfter")
+			steps := make([]pipelineStep, len(cfg.RunAfter.Commands))
+			for i, command := range cfg.RunAfter.Commands {
+				env, err := cfg.CommandEnviron(command.RunCommandConfig)
+				if err != nil {
 					return err
 				}
+				steps[i] = pipelineStep{command.RunCommandConfig, command.PipelineConfig, env}
+			}
+			if err := runPipeline(context.Background(), steps, out); err != nil {
+				return err
 			}
 		}
 
-		if cfg.Debug {
+		if cfg.Debug || err == nil {
 			return err
 		}
-		return ErrNoPrint{}
+		return ErrNoPrint{Err: err}
 	},
 }
 
+// readConfigFile reads the file given with --config or ./.concur.yaml.
+func readConfigFile() error {
+	if cfgFile != "" {
+		viper.SetConfigFile(cfgFile)
+	} else {
+		cwd, err := os.Getwd()
+		cobra.CheckErr(err)
+
+		viper.AddConfigPath(cwd)
+		viper.SetConfigType("yaml")
+		viper.SetConfigName(".concur")
+	}
+
+	return config.ReadInConfig()
+}
+
+// printHeader prints the bold header of a section.
+func printHeader(out io.Writer, name string) {
+	if config.ColorsEnabled() {
+		fmt.Fprintf(out, "\033[1m[%s]\033[0m\n", name)
+	} else {
+		fmt.Fprintf(out, "[%s]\n", name)
+	}
+}
+
+type pipelineStep struct {
+	cfg  config.RunCommandConfig
+	pipe config.PipelineConfig
+	env  []string
+}
+
+// runPipeline runs the commands one after another. A command can read the
+// terminal or the output of the previous command and its output can be
+// shown on out, passed on to the next command or discarded.
+func runPipeline(ctx context.Context, steps []pipelineStep, out io.Writer) error {
+	var previous *bytes.Buffer
+	for _, step := range steps {
+		var stdin io.Reader
+		switch step.pipe.Input {
+		case config.InputTypeStdin:
+			stdin = os.Stdin
+			// A background process group would be stopped when reading the terminal.
+			noProcessGroup := false
+			step.cfg.ProcessGroup = &noProcessGroup
+		case config.InputTypePrevious:
+			stdin = previous
+		}
+
+		var stdout io.Writer = out
+		var next *bytes.Buffer
+		switch step.pipe.Output {
+		case config.OutputTypePrevious:
+			next = &bytes.Buffer{}
+			stdout = next
+		case config.OutputTypeNone:
+			stdout = io.Discard
+		}
+
+		sh := cmd.NewCommand(ctx, step.cfg)
+		sh.SetOutput(out)
+		sh.SetEnv(step.env)
+		if err := sh.RunPiped(stdin, stdout); err != nil {
+			return err
+		}
+		previous = next
+	}
+	return nil
+}
+
 func ExecuteRawMode(ctx context.Context, cfg *config.Config) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -175,25 +283,35 @@ func ExecuteRawMode(ctx context.Context, cfg *config.Config) error {
 	killOthers := cfg.KillOthers
 	killOthersOnFail := cfg.KillOthersOnFail
 
+	commands, err := newCommands(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
 	var wg sync.WaitGroup
 	wg.Add(len(cfg.Commands))
-	errCh := make(chan error, len(cfg.Commands))
-	for _, command := range cfg.Commands {
-		sh := cmd.NewCommand(ctx, cfg.KillSignal.Sys(), command)
-		go func(cmd *cmd.Command) {
+	resultCh := make(chan cmd.Result, len(cfg.Commands))
+	for i, sh := range commands {
+		go func(sh *cmd.Command) {
 			defer wg.Done()
-			err := cmd.RunRaw()
+			err := sh.SuperviseRaw()
+			// The result goes first, so that the results of the commands
+			// killed because of it come after it.
+			resultCh <- cmd.Result{Index: i, Name: sh.Config().Name, Err: err}
 			if killOthers || (err != nil && killOthersOnFail) {
 				cancel()
 			}
-			errCh <- err
 		}(sh)
 	}
 
 	var hcs []healthcheck.HealthChecker
 	if cfg.Status.Enabled {
+		env, err := cfg.Environ()
+		if err != nil {
+			return err
+		}
 		for _, check := range cfg.Status.Checks {
-			hc, err := healthcheck.HealthCheckFactory(check)
+			hc, err := healthcheck.HealthCheckFactory(check, env)
 			if err != nil {
 				return err
 			}
@@ -213,14 +331,18 @@ func ExecuteRawMode(ctx context.Context, cfg *config.Config) error {
 
 	go log.Run(ctx)
 
-	var err error
 	wg.Wait()
 	log.Wait()
-	close(errCh)
-	for errV := range errCh {
-		err = errors.Join(err, errV)
-	}
-	return err
+	return decideSuccess(cfg.Success, resultCh)
+}
+
+// output shows the messages of the concurrently run commands in prefix mode.
+type output interface {
+	GetMessageChannel() chan<- logger.Message
+	SetLogFiles(files []*logger.LogFile)
+	Run(ctx context.Context)
+	Close()
+	Wait()
 }
 
 func ExecutePrefixMode(ctx context.Context, cfg *config.Config) error {
@@ -234,30 +356,79 @@ func ExecutePrefixMode(ctx context.Context, cfg *config.Config) error {
 		return err
 	}
 
-	startedCommands := make([]*cmd.Command, len(cfg.Commands))
+	logFiles, err := openLogFiles(cfg)
+	if err != nil {
+		return err
+	}
+
+	startedCommands, err := newCommands(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.HandleInput {
+		for _, sh := range startedCommands {
+			sh.EnableInput()
+		}
+	}
 
 	for i, command := range cfg.Commands {
-		sh := cmd.NewCommand(ctx, cfg.KillSignal.Sys(), command)
-		pid, err := sh.StartWithPrefix()
-		if err != nil {
-			return err
+		// Commands with dependencies are started later by SuperviseWithPrefix.
+		var pid int
+		if len(command.DependsOn) == 0 {
+			pid, err = startedCommands[i].StartWithPrefix()
+			if err != nil {
+				return err
+			}
 		}
 		index := pref.Add(command.Name, command.Command, pid, &command.PrefixColor)
 		if i != index {
 			return fmt.Errorf("index mismatch: %d != %d", i, index)
 		}
+	}
 
-		startedCommands[i] = sh
+	if daemonState != nil {
+		commands := make([]daemon.CommandState, len(cfg.Commands))
+		for i, command := range cfg.Commands {
+			logFile := cfg.LogFile(i)
+			if logFile != "" {
+				logFile, _ = filepath.Abs(logFile)
+			}
+			commands[i] = daemon.CommandState{
+				Index:   i,
+				Name:    command.Name,
+				Command: command.Command,
+				Pid:     pref.Data(i).Pid,
+				LogFile: logFile,
+			}
+		}
+		if err := daemonState.SetCommands(commands); err != nil {
+			return err
+		}
 	}
 
 	if cfg.Prefix.PadPrefix {
 		pref.ApplyEvenPadding()
 	}
 
+	var hasTTY bool
+	for i, command := range cfg.Commands {
+		if command.TTY {
+			hasTTY = true
+			startedCommands[i].SetPrefixWidth(len(pref.Render(i, false)))
+		}
+	}
+	if hasTTY {
+		go cmd.WatchTerminalSize(ctx, startedCommands)
+	}
+
 	var hcs []healthcheck.HealthChecker
 	if cfg.Status.Enabled {
+		env, err := cfg.Environ()
+		if err != nil {
+			return err
+		}
 		for _, check := range cfg.Status.Checks {
-			hc, err := healthcheck.HealthCheckFactory(check)
+			hc, err := healthcheck.HealthCheckFactory(check, env)
 			if err != nil {
 				return err
 			}
@@ -265,21 +436,82 @@ func ExecutePrefixMode(ctx context.Context, cfg *config.Config) error {
 		}
 	}
 
-	log := logger.NewPrefixLogger(pref, os.Stdout, hcs, cfg.Status)
+	var server *control.Server
+	if cfg.Control.Enabled {
+		path, err := cfg.Control.SocketPath()
+		if err != nil {
+			return err
+		}
+		server = control.NewServer(path, pref, startedCommands, hcs, cancel)
+		if err := server.Listen(); err != nil {
+			return err
+		}
+	}
+
+	var log output
+	if cfg.TUI {
+		log = tui.New(pref, startedCommands, hcs, cancel)
+	} else {
+		var out io.Writer = os.Stdout
+		if cfg.Keyboard {
+			out = keyboard.RawOutput(os.Stdout)
+		}
+		prefixLogger := logger.NewPrefixLogger(pref, out, hcs, cfg.Status, cfg.StderrOnly)
+		prefixLogger.SetOutputFormat(cfg.Output)
+		if cfg.Group {
+			prefixLogger.SetGroupOrder(cfg.GroupOrder)
+		}
+		commandChecks := make([]healthcheck.HealthChecker, len(startedCommands))
+		for i, sh := range startedCommands {
+			commandChecks[i] = sh.HealthChecker()
+		}
+		prefixLogger.SetCommandHealthCheckers(commandChecks)
+		log = prefixLogger
+	}
+	log.SetLogFiles(logFiles)
 	msgCh := log.GetMessageChannel()
+	if server != nil {
+		msgCh = server.Forward(msgCh)
+	}
+
+	var inputHandler *input.Handler
+	if cfg.HandleInput {
+		inputHandler, err = input.NewHandler(pref, startedCommands, cfg.DefaultInputTarget, msgCh)
+		if err != nil {
+			return err
+		}
+		go inputHandler.Run(os.Stdin)
+	}
+
+	var controller *keyboard.Controller
+	if cfg.Keyboard {
+		controller = keyboard.NewController(startedCommands, cancel, msgCh)
+		go func() {
+			if err := controller.Run(os.Stdin); err != nil {
+				fmt.Fprintln(os.Stderr, "keyboard:", err)
+			}
+		}()
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(len(cfg.Commands))
-	errCh := make(chan error, len(cfg.Commands))
+	resultCh := make(chan cmd.Result, len(cfg.Commands))
 
 	for i, sh := range startedCommands {
-		go func(cmd *cmd.Command) {
+		go func(sh *cmd.Command) {
 			defer wg.Done()
-			err := cmd.WaitWithPrefix(i, msgCh)
+			err := sh.SuperviseWithPrefix(i, msgCh, func(pid int) {
+				pref.SetPid(i, pid)
+				if daemonState != nil {
+					_ = daemonState.SetPid(i, pid)
+				}
+			})
+			// The result goes first, so that the results of the commands
+			// killed because of it come after it.
+			resultCh <- cmd.Result{Index: i, Name: sh.Config().Name, Err: err}
 			if killOthers || (err != nil && killOthersOnFail) {
 				cancel()
 			}
-			errCh <- err
 		}(sh)
 	}
 
@@ -293,41 +525,116 @@ func ExecutePrefixMode(ctx context.Context, cfg *config.Config) error {
 
 	go log.Run(ctx)
 
-	go killAfterTimeout(ctx, startedCommands)
-
 	wg.Wait()
+	if inputHandler != nil {
+		inputHandler.Stop()
+	}
+	if controller != nil {
+		controller.Stop()
+	}
+	if server != nil {
+		server.Close()
+	}
 	log.Close()
 	log.Wait()
-	close(errCh)
-	for errV := range errCh {
-		err = errors.Join(err, errV)
+	return decideSuccess(cfg.Success, resultCh)
+}
+
+// openLogFiles opens the log files of the commands, indexed by the command.
+func openLogFiles(cfg *config.Config) ([]*logger.LogFile, error) {
+	logFiles := make([]*logger.LogFile, len(cfg.Commands))
+	for i := range cfg.Commands {
+		path := cfg.LogFile(i)
+		if path == "" {
+			continue
+		}
+		f, err := logger.OpenLogFile(path, cfg.Log)
+		if err != nil {
+			for _, f := range logFiles[:i] {
+				if f != nil {
+					_ = f.Close()
+				}
+			}
+			return nil, err
+		}
+		logFiles[i] = f
 	}
-	return err
+	return logFiles, nil
 }
 
-func killAfterTimeout(ctx context.Context, commands []*cmd.Command) {
-	<-ctx.Done()
-	<-time.After(5 * time.Second)
-	for _, cmd := range commands {
-		cmd.Kill()
+// decideSuccess collects the results in completion order and decides the
+// exit code according to the success condition.
+func decideSuccess(cond config.SuccessCondition, resultCh chan cmd.Result) error {
+	close(resultCh)
+	results := make([]cmd.Result, 0, cap(resultCh))
+	for result := range resultCh {
+		results = append(results, result)
+	}
+	return cmd.Decide(cond, results)
+}
+
+// newCommands creates the concurrently run commands, wires up their
+// dependencies and starts the health checks they depend on.
+func newCommands(ctx context.Context, cfg *config.Config) ([]*cmd.Command, error) {
+	commands := make([]*cmd.Command, len(cfg.Commands))
+	byName := make(map[string]*cmd.Command, len(cfg.Commands))
+	for i, command := range cfg.Commands {
+		env, err := cfg.CommandEnviron(command)
+		if err != nil {
+			return nil, err
+		}
+		commands[i] = cmd.NewCommand(ctx, command)
+		commands[i].SetEnv(env)
+		if command.Name != "" {
+			byName[command.Name] = commands[i]
+		}
+
+		if command.HealthCheck != nil {
+			hc, err := healthcheck.HealthCheckFactory(*command.HealthCheck, env)
+			if err != nil {
+				return nil, err
+			}
+			commands[i].SetHealthChecker(hc)
+			go hc.Start(ctx)
+		}
 	}
+
+	for i, command := range cfg.Commands {
+		deps := make([]cmd.Dependency, len(command.DependsOn))
+		for j, dep := range command.DependsOn {
+			deps[j] = cmd.Dependency{Command: byName[dep.Name], Condition: dep.GetCondition()}
+		}
+		commands[i].SetDependencies(deps)
+	}
+	return commands, nil
 }
 
-type ErrNoPrint struct{}
+// ErrNoPrint wraps an error that was already reported by the command output.
+type ErrNoPrint struct {
+	Err error
+}
 
 func (ErrNoPrint) Error() string {
 	return ""
 }
 
+func (e ErrNoPrint) Unwrap() error {
+	return e.Err
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func ExecuteContext(ctx context.Context, version string) {
 	rootCmd.Version = version
 	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
-		if !errors.Is(err, ErrNoPrint{}) {
+		if !errors.As(err, &ErrNoPrint{}) {
 			fmt.Println("Error:", err)
 		}
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
@@ -343,7 +650,7 @@ func init() {
 
 	rootCmd.Flags().StringArrayVarP(&prefixColors, "prefix-colors", "c", nil, "Prefix Colors")
 
-	rootCmd.Flags().StringP("prefix", "p", "", "Prefix Type (values: index, name, command, pid, time, TEMPLATE)\n  template Values: {{.Name | .Index | .Command | .Pid | .Time}}")
+	rootCmd.Flags().StringP("prefix", "p", "", "Prefix Type (values: index, name, command, pid, time, TEMPLATE)\n  template Values: {{.Name | .Index | .Command | .Pid | .Time | .Stream}}")
 	viper.BindPFlag("prefix.template", rootCmd.Flags().Lookup("prefix"))
 
 	rootCmd.Flags().Int("prefix-length", 10, "Prefix Length")
@@ -358,6 +665,39 @@ func init() {
 	rootCmd.Flags().Bool("time-since-start", false, "Show time since start")
 	viper.BindPFlag("prefix.timeSinceStart", rootCmd.Flags().Lookup("time-since-start"))
 
+	rootCmd.Flags().String("output", "text", "Output format of the commands (values: text, json)")
+	viper.BindPFlag("output", rootCmd.Flags().Lookup("output"))
+
+	rootCmd.Flags().Bool("group", false, "Print the output of each command as one block once it is done")
+	viper.BindPFlag("group", rootCmd.Flags().Lookup("group"))
+
+	rootCmd.Flags().String("group-order", "config", "Order of the blocks in group mode (values: config, completion)")
+	viper.BindPFlag("groupOrder", rootCmd.Flags().Lookup("group-order"))
+
+	rootCmd.Flags().String("color", "auto", "When to write colors (values: auto, always, never)")
+	viper.BindPFlag("colorMode", rootCmd.Flags().Lookup("color"))
+
+	rootCmd.Flags().String("auto-color", "palette", "Color of commands without a color (values: palette, hash, none)")
+	viper.BindPFlag("prefix.autoColor", rootCmd.Flags().Lookup("auto-color"))
+
+	rootCmd.Flags().Bool("tui", false, "Show the commands in a full-screen terminal UI")
+	viper.BindPFlag("tui", rootCmd.Flags().Lookup("tui"))
+
+	rootCmd.Flags().Bool("keyboard", false, "Control the commands with keys: r<index> restart, s<index> stop, l list, c clear, q quit")
+	viper.BindPFlag("keyboard", rootCmd.Flags().Lookup("keyboard"))
+
+	rootCmd.Flags().Bool("control", false, "Serve the control socket for concur ctl")
+	viper.BindPFlag("control.enabled", rootCmd.Flags().Lookup("control"))
+
+	rootCmd.Flags().String("control-socket", "", "Path of the control socket (default: under $XDG_RUNTIME_DIR)")
+	viper.BindPFlag("control.socket", rootCmd.Flags().Lookup("control-socket"))
+
+	rootCmd.Flags().String("stderr-marker", "", "Marker added to the prefix of lines written to stderr")
+	viper.BindPFlag("prefix.stderr.marker", rootCmd.Flags().Lookup("stderr-marker"))
+
+	rootCmd.Flags().Bool("stderr-only", false, "Only show lines written to stderr and the messages of concur (prefix mode only)")
+	viper.BindPFlag("stderrOnly", rootCmd.Flags().Lookup("stderr-only"))
+
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("raw", "r", false, "Raw mode (send output of each command directly)")
@@ -374,4 +714,20 @@ func init() {
 
 	rootCmd.Flags().String("kill-signal", "SIGINT", "Signal to send to kill other commands")
 	viper.BindPFlag("killSignal", rootCmd.Flags().Lookup("kill-signal"))
+
+	rootCmd.Flags().String("success", "all", "Which commands have to succeed (values: all, first, last, command-{name|index}, !command-{name|index})")
+	viper.BindPFlag("success", rootCmd.Flags().Lookup("success"))
+
+	rootCmd.Flags().Bool("handle-input", false, "Forward input to the commands, \"<name|index>:text\" sends to a specific command")
+	viper.BindPFlag("handleInput", rootCmd.Flags().Lookup("handle-input"))
+
+	rootCmd.Flags().String("default-input-target", "0", "The command that receives input without a target (name or index)")
+	viper.BindPFlag("defaultInputTarget", rootCmd.Flags().Lookup("default-input-target"))
+
+	rootCmd.Flags().Bool("process-group", true, "Run each command in its own process group and signal the whole group")
+	viper.BindPFlag("processGroup", rootCmd.Flags().Lookup("process-group"))
+
+	// up runs the commands like the root command and shares its flags.
+	upCmd.PreRunE = rootCmd.PreRunE
+	upCmd.Flags().AddFlagSet(rootCmd.Flags())
 }
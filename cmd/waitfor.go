@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/akatranlp/concur/internal/config"
+	healthcheck "github.com/akatranlp/concur/internal/health_check"
+	"github.com/akatranlp/concur/internal/logger"
+)
+
+// waitFor blocks until all checks of waitFor are healthy. On timeout it
+// shows the last result of every check that is not healthy.
+func waitFor(ctx context.Context, cfg *config.Config, out io.Writer) error {
+	env, err := cfg.Environ()
+	if err != nil {
+		return err
+	}
+	checks := cfg.WaitFor.AllChecks(cfg.Status)
+	checkers := make([]healthcheck.HealthChecker, len(checks))
+	for i, check := range checks {
+		checker, err := healthcheck.HealthCheckFactory(check, env)
+		if err != nil {
+			return err
+		}
+		checkers[i] = checker
+	}
+
+	// Without a timeout it waits until concur is stopped.
+	var waitCtx context.Context
+	var cancel context.CancelFunc
+	if cfg.WaitFor.Timeout > 0 {
+		waitCtx, cancel = context.WithTimeout(ctx, cfg.WaitFor.Timeout)
+	} else {
+		waitCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+	for _, checker := range checkers {
+		go checker.Start(waitCtx)
+	}
+
+	start := time.Now()
+	pending := make(map[int]bool, len(checkers))
+	for i := range checkers {
+		pending[i] = true
+	}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		for i, checker := range checkers {
+			if pending[i] && checker.Healthy() {
+				delete(pending, i)
+				fmt.Fprintf(out, "%s is healthy after %s\n", checkTarget(checks[i]), time.Since(start).Round(time.Millisecond))
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return errors.New("interrupted while waiting for the checks")
+			}
+			return timedOut(cfg.WaitFor.Timeout, checks, checkers, pending, out)
+		}
+	}
+}
+
+// timedOut shows the last result of the checks that are not healthy and
+// returns the error naming them.
+func timedOut(timeout time.Duration, checks []config.StatusCheckConfig, checkers []healthcheck.HealthChecker, pending map[int]bool, out io.Writer) error {
+	var targets []string
+	for i, check := range checks {
+		if !pending[i] {
+			continue
+		}
+		targets = append(targets, checkTarget(check))
+		fmt.Fprintf(out, "%s is %s:\n", checkTarget(check), checkers[i].Status())
+		for _, message := range checkers[i].Messages() {
+			if config.ColorsEnabled() {
+				message += "\033[0m"
+			} else {
+				message = logger.StripANSI(message)
+			}
+			fmt.Fprintf(out, "  %s\n", message)
+		}
+	}
+	return fmt.Errorf("waitFor timed out after %s waiting for %s", timeout, strings.Join(targets, ", "))
+}
+
+// checkTarget describes what a check checks.
+func checkTarget(check config.StatusCheckConfig) string {
+	switch check.Type {
+	case config.CheckTypeCommand:
+		return check.Command
+	case config.CheckTypeHTTP:
+		return check.URL
+	case config.CheckTypeGRPC:
+		if check.Service != "" {
+			return check.Address + "/" + check.Service
+		}
+	}
+	return check.Address
+}
@@ -1,82 +1,259 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
+	"regexp"
 	"runtime"
 	"strconv"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/akatranlp/concur/internal/config"
+	healthcheck "github.com/akatranlp/concur/internal/health_check"
 	"github.com/akatranlp/concur/internal/logger"
 )
 
 type Command struct {
+	ctx context.Context
 	cfg config.RunCommandConfig
-	cmd *exec.Cmd
-	r   *os.File
-	w   *os.File
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	exited chan struct{}
+	r      *os.File
+	w      *os.File
+	errR   *os.File
+	errW   *os.File
+	stdin  io.WriteCloser
+	ptmx   *os.File
+	// pipes are the write ends of the output pipes of raw mode, see fileWriter.
+	pipes   []*os.File
+	copying sync.WaitGroup
+
+	deps          []Dependency
+	healthChecker healthcheck.HealthChecker
+	startedOnce   sync.Once
+	started       chan struct{}
+	finishOnce    sync.Once
+	finished      chan struct{}
+	err           error
+	readyWhen     *regexp.Regexp
+	readyOnce     sync.Once
+	ready         chan struct{}
+	handleInput   bool
+	prefixWidth   int
+	startedAt     time.Time
+	out           io.Writer
+	env           []string
+
+	// Guarded by mu, see control.go.
+	state     State
+	req       request
+	requested chan struct{}
+	runCancel context.CancelFunc
+}
+
+func NewCommand(ctx context.Context, cfg config.RunCommandConfig) *Command {
+	c := &Command{
+		ctx:       ctx,
+		cfg:       cfg,
+		started:   make(chan struct{}),
+		finished:  make(chan struct{}),
+		ready:     make(chan struct{}),
+		out:       os.Stdout,
+		state:     StateWaiting,
+		requested: make(chan struct{}, 1),
+	}
+	if cfg.ReadyWhen != "" {
+		// The pattern was already validated by the config.
+		c.readyWhen = regexp.MustCompile(cfg.ReadyWhen)
+	}
+	return c
 }
 
-func NewCommand(ctx context.Context, killSignal syscall.Signal, cfg config.RunCommandConfig) *Command {
+func (c *Command) Config() config.RunCommandConfig {
+	return c.cfg
+}
+
+// SetOutput sets where the output and the messages of the command are
+// written to in raw mode, which is os.Stdout by default.
+func (c *Command) SetOutput(w io.Writer) {
+	c.out = w
+}
+
+// SetEnv sets the environment of the command, which is the one of concur
+// by default.
+func (c *Command) SetEnv(env []string) {
+	c.env = env
+}
+
+// newExecCmd creates a fresh exec.Cmd, because an exec.Cmd can only be started once.
+func (c *Command) newExecCmd() *exec.Cmd {
 	var arg0, arg1 string
 	if runtime.GOOS == "windows" {
 		arg0, arg1 = "cmd", "/c"
 	} else {
 		arg0, arg1 = "sh", "-c"
 	}
-	cmd := exec.CommandContext(ctx, arg0, arg1, cfg.Command)
+	// Every process gets its own context, so that it can be stopped on its own.
+	runCtx, cancel := context.WithCancel(c.ctx)
+	cmd := exec.CommandContext(runCtx, arg0, arg1, c.cfg.Command)
 
+	exited := make(chan struct{})
 	cmd.Cancel = func() error {
-		var err error
-		if runtime.GOOS == "windows" {
-			err = cmd.Process.Kill()
-		} else {
-			// Check which signal is the best to use SIGINT or SIGTERM or SIGKILL
-			err = cmd.Process.Signal(killSignal)
-		}
-		return err
+		return c.shutdown(cmd.Process, exited)
 	}
-	cmd.Dir = cfg.CWD
-	return &Command{cfg: cfg, cmd: cmd}
+	cmd.Dir = c.cfg.CWD
+	cmd.Env = c.env
+	if c.cfg.UseProcessGroup() {
+		setProcessGroup(cmd)
+	}
+	c.exited = exited
+	c.runCancel = cancel
+	return cmd
 }
 
-func (c *Command) Kill() error {
-	return c.cmd.Process.Kill()
+// wait waits for the current process and lets a running shutdown know that
+// the process exited. Processes of its group that outlived it are shut down
+// as well, because they would keep its output open.
+func (c *Command) wait() error {
+	err := c.cmd.Wait()
+	close(c.exited)
+	if c.cfg.UseProcessGroup() && groupAlive(c.cmd.Process) {
+		_ = c.shutdown(c.cmd.Process, c.exited)
+	}
+	for _, w := range c.pipes {
+		_ = w.Close()
+	}
+	c.pipes = nil
+	c.copying.Wait()
+
+	c.mu.Lock()
+	cancel := c.runCancel
+	c.runCancel = nil
+	c.mu.Unlock()
+	cancel()
+	return err
 }
 
 func (c *Command) StartWithPrefix() (pid int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cfg.TTY {
+		return c.startTTY()
+	}
+
 	r, w, err := os.Pipe()
 	if err != nil {
 		return -1, err
 	}
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		_ = r.Close()
+		_ = w.Close()
+		return -1, err
+	}
+	closePipes := func() {
+		_ = r.Close()
+		_ = w.Close()
+		_ = errR.Close()
+		_ = errW.Close()
+	}
 
+	c.cmd = c.newExecCmd()
 	c.cmd.Stdout = w
-	c.cmd.Stderr = w
+	c.cmd.Stderr = errW
 	c.r = r
 	c.w = w
+	c.errR = errR
+	c.errW = errW
+	c.stdin = nil
+	if c.handleInput {
+		if c.stdin, err = c.cmd.StdinPipe(); err != nil {
+			closePipes()
+			return -1, err
+		}
+	}
 
 	if err := c.cmd.Start(); err != nil {
+		closePipes()
 		return -1, err
 	}
+	c.startedAt = time.Now()
+	c.state = StateRunning
+	c.markStarted()
 
 	return c.cmd.Process.Pid, nil
 }
 
 func (c *Command) StartRaw() error {
-	c.cmd.Stderr = os.Stderr
-	c.cmd.Stdout = os.Stdout
+	return c.startRaw(nil, c.out)
+}
 
-	return c.cmd.Start()
+func (c *Command) startRaw(stdin io.Reader, stdout io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var stderr io.Writer = os.Stderr
+	if c.readyWhen != nil && !c.IsReady() {
+		stderr = &readyWriter{out: os.Stderr, c: c}
+		stdout = &readyWriter{out: stdout, c: c}
+	}
+
+	c.cmd = c.newExecCmd()
+	c.cmd.Stdin = stdin
+	var err error
+	if c.cmd.Stdout, err = c.fileWriter(stdout); err == nil {
+		c.cmd.Stderr, err = c.fileWriter(stderr)
+	}
+	if err == nil {
+		err = c.cmd.Start()
+	}
+	if err != nil {
+		for _, w := range c.pipes {
+			_ = w.Close()
+		}
+		c.pipes = nil
+		return err
+	}
+	c.startedAt = time.Now()
+	c.state = StateRunning
+	c.markStarted()
+	return nil
+}
+
+// fileWriter returns w if it is a file and otherwise the write end of a pipe
+// copying to w. exec.Cmd.Wait would wait for the copying, which only ends
+// once every process holding the output open exited, so it would not return
+// while a process started in the background is still running.
+func (c *Command) fileWriter(w io.Writer) (*os.File, error) {
+	if f, ok := w.(*os.File); ok {
+		return f, nil
+	}
+	r, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	c.pipes = append(c.pipes, pw)
+	c.copying.Add(1)
+	go func() {
+		defer c.copying.Done()
+		_, _ = io.Copy(w, r)
+		_ = r.Close()
+	}()
+	return pw, nil
 }
 
 func (c *Command) WaitRaw() error {
-	err := c.cmd.Wait()
-	fmt.Printf("%s exited with %s\n", c.cfg.Command, c.cmd.ProcessState)
+	err := c.wait()
+	fmt.Fprintf(c.out, "%s exited with %s\n", c.cfg.Command, c.cmd.ProcessState)
 	return err
 }
 
@@ -87,32 +264,80 @@ func (c *Command) RunRaw() error {
 	return c.WaitRaw()
 }
 
+// RunPiped runs the command like RunRaw, but reads from stdin and writes
+// its output to stdout. Errors are still written to os.Stderr.
+func (c *Command) RunPiped(stdin io.Reader, stdout io.Writer) error {
+	if err := c.startRaw(stdin, stdout); err != nil {
+		return err
+	}
+	return c.WaitRaw()
+}
+
 func (c *Command) WaitWithPrefix(id int, msgCh chan<- logger.Message) error {
-	done := make(chan struct{})
-	go func() {
-		defer close(done)
-
-		scanner := bufio.NewScanner(c.r)
-		scanner.Split(bufio.ScanLines)
-		for scanner.Scan() {
-			text := scanner.Text() + "\n\033[0m"
-			msgCh <- logger.Message{ID: id, Text: text}
-
-			if c.cfg.Debug {
-				for _, b := range text {
-					fmt.Printf("%s", strconv.QuoteRuneToASCII(rune(b)))
-				}
-				fmt.Println()
-			}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func(r *os.File) {
+		defer wg.Done()
+		c.scan(r, id, logger.StreamStdout, msgCh)
+	}(c.r)
+	if c.errR != nil {
+		wg.Add(1)
+		go func(r *os.File) {
+			defer wg.Done()
+			c.scan(r, id, logger.StreamStderr, msgCh)
+		}(c.errR)
+	}
+
+	err := c.wait()
+	for _, w := range []*os.File{c.w, c.errW} {
+		if w != nil {
+			_ = w.Close()
 		}
-		if err := scanner.Err(); err != nil {
-			log.Println(err)
+	}
+	wg.Wait()
+	c.mu.Lock()
+	_ = c.r.Close()
+	if c.errR != nil {
+		_ = c.errR.Close()
+	}
+	c.ptmx = nil
+	c.mu.Unlock()
+	msgCh <- logger.Message{ID: id, Text: fmt.Sprintf("%s exited with %s\n", c.cfg.Command, c.cmd.ProcessState), Event: c.exitEvent()}
+	return err
+}
+
+func (c *Command) exitEvent() *logger.Event {
+	state := c.cmd.ProcessState
+	event := &logger.Event{
+		Type:     logger.EventExit,
+		Pid:      state.Pid(),
+		Code:     state.ExitCode(),
+		Duration: time.Since(c.startedAt),
+	}
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		event.Code = 128 + int(status.Signal())
+		event.Signal = config.KillSignal(status.Signal()).String()
+	}
+	return event
+}
+
+// scan sends every line read from r to msgCh as part of the given stream.
+func (c *Command) scan(r *os.File, id int, stream logger.Stream, msgCh chan<- logger.Message) {
+	err := readLines(ttyReader{r}, maxLineLength, lineIdleTimeout, func(line string) {
+		text := line + "\n"
+		msgCh <- logger.Message{ID: id, Text: text, Stream: stream}
+		if c.matchReady(line) {
+			msgCh <- logger.Message{ID: id, Text: fmt.Sprintf("%s is ready\n", c.displayName()), Event: &logger.Event{Type: logger.EventReady}}
 		}
-	}()
 
-	err := c.cmd.Wait()
-	_ = c.w.Close()
-	<-done
-	msgCh <- logger.Message{ID: id, Text: fmt.Sprintf("%s exited with %s\n", c.cfg.Command, c.cmd.ProcessState)}
-	return err
+		if c.cfg.Debug {
+			for _, b := range text {
+				fmt.Printf("%s", strconv.QuoteRuneToASCII(rune(b)))
+			}
+			fmt.Println()
+		}
+	})
+	if err != nil {
+		log.Println(err)
+	}
 }
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// State is the state of a supervised command.
+type State string
+
+const (
+	StateWaiting    State = "waiting"
+	StateRunning    State = "running"
+	StateRestarting State = "restarting"
+	StateStopped    State = "stopped"
+	StateExited     State = "exited"
+)
+
+type request int
+
+const (
+	requestNone request = iota
+	requestRestart
+	requestStop
+)
+
+var (
+	ErrNotSupervised = errors.New("command is not supervised anymore")
+	ErrNotStopped    = errors.New("command is not stopped")
+	ErrNotRunning    = errors.New("command is not running")
+)
+
+// Restart stops the running process of the command and starts it again,
+// independent of its restart policy. A stopped command is started again.
+func (c *Command) Restart() error {
+	return c.request(requestRestart)
+}
+
+// Stop stops the running process of the command without restarting it,
+// until Restart is called.
+func (c *Command) Stop() error {
+	return c.request(requestStop)
+}
+
+// Start starts a stopped command again.
+func (c *Command) Start() error {
+	if c.State() != StateStopped {
+		return ErrNotStopped
+	}
+	return c.request(requestRestart)
+}
+
+// Signal sends the signal to the running process, with process groups to
+// every process it started. The restart policy applies if it exits.
+func (c *Command) Signal(sig syscall.Signal) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state != StateRunning || c.cmd == nil || c.cmd.Process == nil {
+		return ErrNotRunning
+	}
+	return signalProcess(c.cmd.Process, sig, c.cfg.UseProcessGroup())
+}
+
+func (c *Command) request(r request) error {
+	c.mu.Lock()
+	if c.state == StateExited {
+		c.mu.Unlock()
+		return ErrNotSupervised
+	}
+	c.req = r
+	select {
+	case c.requested <- struct{}{}:
+	default:
+	}
+	cancel := c.runCancel
+	c.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+// takeRequest returns and clears the pending request.
+func (c *Command) takeRequest() request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.requested:
+	default:
+	}
+	r := c.req
+	c.req = requestNone
+	return r
+}
+
+func (c *Command) hasRequest() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.req != requestNone
+}
+
+// waitRequest blocks until a request is made and reports false if the
+// command context was cancelled in the meantime.
+func (c *Command) waitRequest() bool {
+	for !c.hasRequest() {
+		select {
+		case <-c.ctx.Done():
+			return false
+		case <-c.requested:
+		}
+	}
+	return true
+}
+
+// State returns the current state of the command.
+func (c *Command) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+func (c *Command) setState(state State) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state = state
+}
+
+// Pid returns the pid of the running process or 0.
+func (c *Command) Pid() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state != StateRunning || c.cmd == nil || c.cmd.Process == nil {
+		return 0
+	}
+	return c.cmd.Process.Pid
+}
+
+// StartedAt returns when the last process of the command was started.
+func (c *Command) StartedAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.startedAt
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/akatranlp/concur/internal/config"
+	healthcheck "github.com/akatranlp/concur/internal/health_check"
+)
+
+// Dependency is a command that has to reach a condition before the
+// depending command is started.
+type Dependency struct {
+	Command   *Command
+	Condition config.DependencyCondition
+}
+
+// SetDependencies sets the commands that have to reach their condition
+// before this command is started.
+func (c *Command) SetDependencies(deps []Dependency) {
+	c.deps = deps
+}
+
+// SetHealthChecker sets the health checker that decides whether the command
+// is healthy. The caller is responsible for starting it.
+func (c *Command) SetHealthChecker(hc healthcheck.HealthChecker) {
+	c.healthChecker = hc
+}
+
+// HealthChecker returns the health checker of the command or nil.
+func (c *Command) HealthChecker() healthcheck.HealthChecker {
+	return c.healthChecker
+}
+
+// WaitFor blocks until the command reached the given condition or returns an
+// error if it never can.
+func (c *Command) WaitFor(ctx context.Context, cond config.DependencyCondition) error {
+	switch cond {
+	case config.DependencyConditionStarted:
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.started:
+			return nil
+		case <-c.finished:
+			select {
+			case <-c.started:
+				return nil
+			default:
+				return fmt.Errorf("%s was never started", c.displayName())
+			}
+		}
+	case config.DependencyConditionReady:
+		return c.waitReady(ctx)
+	case config.DependencyConditionCompleted:
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.finished:
+			if c.err != nil {
+				return fmt.Errorf("%s did not complete successfully: %w", c.displayName(), c.err)
+			}
+			return nil
+		}
+	case config.DependencyConditionHealthy:
+		if c.healthChecker == nil {
+			return fmt.Errorf("%s has no health check", c.displayName())
+		}
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		finished := c.finished
+		for {
+			if c.healthChecker.Healthy() {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-finished:
+				// A command that exited successfully may have started something
+				// in the background (e.g. docker compose up -d) that becomes healthy later.
+				if c.err != nil {
+					return fmt.Errorf("%s exited before it became healthy: %w", c.displayName(), c.err)
+				}
+				// The closed channel would be selected again right away.
+				finished = nil
+			case <-ticker.C:
+			}
+		}
+	}
+	return fmt.Errorf("invalid dependency condition: %s", cond)
+}
+
+func (c *Command) waitDependencies() error {
+	for _, dep := range c.deps {
+		if err := dep.Command.WaitFor(c.ctx, dep.Condition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Command) displayName() string {
+	if c.cfg.Name != "" {
+		return c.cfg.Name
+	}
+	return c.cfg.Command
+}
+
+func (c *Command) markStarted() {
+	c.startedOnce.Do(func() { close(c.started) })
+}
+
+// finish marks that the command will not be run anymore.
+func (c *Command) finish(err error) {
+	c.finishOnce.Do(func() {
+		c.err = err
+		close(c.finished)
+	})
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/akatranlp/concur/internal/config"
+	healthcheck "github.com/akatranlp/concur/internal/health_check"
+)
+
+// fakeChecker becomes healthy at a given time and counts how often it was asked.
+type fakeChecker struct {
+	healthyAt time.Time
+	calls     atomic.Int64
+}
+
+func (c *fakeChecker) Start(context.Context) {}
+
+func (c *fakeChecker) GetHealthCheckMessage(context.Context) ([]string, int) { return nil, 0 }
+
+func (c *fakeChecker) Messages() []string { return nil }
+
+func (c *fakeChecker) Status() healthcheck.Status {
+	if c.Healthy() {
+		return healthcheck.StatusHealthy
+	}
+	return healthcheck.StatusStarting
+}
+
+func (c *fakeChecker) Healthy() bool {
+	c.calls.Add(1)
+	return time.Now().After(c.healthyAt)
+}
+
+func TestWaitForHealthyAfterSuccessfulExit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Like docker compose up -d, the command exits before it is healthy.
+	dep := NewCommand(ctx, config.RunCommandConfig{Command: "true"})
+	checker := &fakeChecker{healthyAt: time.Now().Add(500 * time.Millisecond)}
+	dep.SetHealthChecker(checker)
+	dep.finish(nil)
+
+	if err := dep.WaitFor(ctx, config.DependencyConditionHealthy); err != nil {
+		t.Fatal(err)
+	}
+	// The ticker checks every 100ms, a busy loop would call it millions of times.
+	if calls := checker.calls.Load(); calls > 20 {
+		t.Errorf("Healthy was called %d times, the wait is spinning", calls)
+	}
+}
+
+func TestWaitForHealthyFailedExit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dep := NewCommand(ctx, config.RunCommandConfig{Command: "false"})
+	dep.SetHealthChecker(&fakeChecker{healthyAt: time.Now().Add(time.Hour)})
+	dep.finish(context.Canceled)
+
+	if err := dep.WaitFor(ctx, config.DependencyConditionHealthy); err == nil {
+		t.Fatal("expected an error for a dependency that failed before it became healthy")
+	}
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"errors"
+)
+
+var ErrNoInput = errors.New("command does not accept input")
+
+// EnableInput connects the stdin of the command to a pipe on the next start
+// with StartWithPrefix, so that WriteInput can forward input to it.
+func (c *Command) EnableInput() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handleInput = true
+}
+
+// WriteInput writes to the stdin of the running process.
+func (c *Command) WriteInput(p []byte) (int, error) {
+	c.mu.Lock()
+	stdin := c.stdin
+	c.mu.Unlock()
+
+	if stdin == nil {
+		return 0, ErrNoInput
+	}
+	return stdin.Write(p)
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// maxLineLength is the length after which a line is split into several lines.
+	maxLineLength = 64 * 1024
+	// lineIdleTimeout is the time after which a partial line without a
+	// newline, like a prompt, is flushed.
+	lineIdleTimeout = 200 * time.Millisecond
+)
+
+// readLines calls emit for every line read from r without the line ending.
+// Lines longer than maxLength are split, and a partial line is emitted once
+// no more output arrived for idleTimeout. It returns nil at io.EOF.
+func readLines(r io.Reader, maxLength int, idleTimeout time.Duration, emit func(line string)) error {
+	type chunk struct {
+		data []byte
+		err  error
+	}
+	chunks := make(chan chunk)
+	go func() {
+		for {
+			buf := make([]byte, 32*1024)
+			n, err := r.Read(buf)
+			chunks <- chunk{data: buf[:n], err: err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	timer := time.NewTimer(idleTimeout)
+	timer.Stop()
+	defer timer.Stop()
+
+	var line []byte
+	for {
+		select {
+		case c := <-chunks:
+			line = append(line, c.data...)
+			for {
+				if i := bytes.IndexByte(line, '\n'); i >= 0 && i <= maxLength {
+					emit(string(bytes.TrimSuffix(line[:i], []byte{'\r'})))
+					line = line[i+1:]
+				} else if len(line) > maxLength {
+					n := splitIndex(line, maxLength)
+					emit(string(line[:n]))
+					line = line[n:]
+				} else {
+					break
+				}
+			}
+
+			if c.err != nil {
+				if len(line) > 0 {
+					emit(string(line))
+				}
+				if errors.Is(c.err, io.EOF) {
+					return nil
+				}
+				return c.err
+			}
+
+			if len(line) > 0 {
+				timer.Reset(idleTimeout)
+			} else {
+				timer.Stop()
+			}
+		case <-timer.C:
+			if len(line) > 0 {
+				emit(string(line))
+				line = nil
+			}
+		}
+	}
+}
+
+// splitIndex returns the index at which a line longer than maxLength is
+// split without cutting a UTF-8 encoded rune in half.
+func splitIndex(line []byte, maxLength int) int {
+	for n := maxLength; n > maxLength-utf8.UTFMax && n > 0; n-- {
+		if utf8.RuneStart(line[n]) {
+			return n
+		}
+	}
+	return maxLength
+}
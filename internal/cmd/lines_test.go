@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestReadLines(t *testing.T) {
+	long := strings.Repeat("a", 150_000)
+
+	tests := []struct {
+		name      string
+		writes    []string
+		pause     time.Duration
+		maxLength int
+		idle      time.Duration
+		want      []string
+	}{
+		{
+			name:      "lines",
+			writes:    []string{"one\ntwo\n"},
+			maxLength: maxLineLength,
+			want:      []string{"one", "two"},
+		},
+		{
+			name:      "crlf",
+			writes:    []string{"one\r\ntwo\r\n"},
+			maxLength: maxLineLength,
+			want:      []string{"one", "two"},
+		},
+		{
+			name:      "line split over writes",
+			writes:    []string{"o", "ne\nt", "wo\n"},
+			maxLength: maxLineLength,
+			want:      []string{"one", "two"},
+		},
+		{
+			name:      "partial last line",
+			writes:    []string{"one\ntwo"},
+			maxLength: maxLineLength,
+			want:      []string{"one", "two"},
+		},
+		{
+			name:      "longer than max length",
+			writes:    []string{long + "\nend\n"},
+			maxLength: maxLineLength,
+			want:      []string{long[:maxLineLength], long[maxLineLength : 2*maxLineLength], long[2*maxLineLength:], "end"},
+		},
+		{
+			name:      "max length with newline",
+			writes:    []string{"abcd\nefghi\n"},
+			maxLength: 4,
+			want:      []string{"abcd", "efgh", "i"},
+		},
+		{
+			name:      "rune at the cut",
+			writes:    []string{"abc€def\n"},
+			maxLength: 4,
+			want:      []string{"abc", "€d", "ef"},
+		},
+		{
+			name:      "idle flush of a partial line",
+			writes:    []string{"prompt> ", "answer\n"},
+			pause:     200 * time.Millisecond,
+			maxLength: maxLineLength,
+			idle:      50 * time.Millisecond,
+			want:      []string{"prompt> ", "answer"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idle := tt.idle
+			if idle == 0 {
+				idle = time.Minute
+			}
+
+			r, w := io.Pipe()
+			go func() {
+				for i, write := range tt.writes {
+					if i > 0 {
+						time.Sleep(tt.pause)
+					}
+					_, _ = w.Write([]byte(write))
+				}
+				_ = w.Close()
+			}()
+
+			var got []string
+			err := readLines(r, tt.maxLength, idle, func(line string) {
+				got = append(got, line)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d lines %q, want %d lines %q", len(got), shorten(got), len(tt.want), shorten(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("line %d = %q, want %q", i, shorten(got[i:i+1]), shorten(tt.want[i:i+1]))
+				}
+				if !utf8.ValidString(got[i]) {
+					t.Errorf("line %d is not valid UTF-8: %q", i, got[i])
+				}
+			}
+		})
+	}
+}
+
+// shorten keeps failure messages of long lines readable.
+func shorten(lines []string) []string {
+	short := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) > 40 {
+			line = line[:20] + "…" + line[len(line)-20:]
+		}
+		short[i] = line
+	}
+	return short
+}
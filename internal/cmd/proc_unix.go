@@ -0,0 +1,36 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup puts the command into its own process group, so that
+// signals can reach every process it started.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalProcess sends the signal to the process or to its whole process group.
+func signalProcess(process *os.Process, sig syscall.Signal, group bool) error {
+	if !group {
+		return process.Signal(sig)
+	}
+	err := syscall.Kill(-process.Pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// groupAlive reports whether any process of the process group is still running.
+func groupAlive(process *os.Process) bool {
+	return syscall.Kill(-process.Pid, 0) == nil
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup is a no-op on windows, where commands are killed directly.
+func setProcessGroup(*exec.Cmd) {}
+
+// signalProcess kills the process, because windows does not support signals.
+func signalProcess(process *os.Process, _ syscall.Signal, _ bool) error {
+	return process.Kill()
+}
+
+func groupAlive(*os.Process) bool {
+	return false
+}
@@ -0,0 +1,108 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// startTTY starts the command in a pseudo-terminal, so that it keeps its
+// colors and progress output. Has to be called with c.mu held.
+func (c *Command) startTTY() (pid int, err error) {
+	c.cmd = c.newExecCmd()
+	// pty.Start puts the command into a new session, which is already a new
+	// process group, and a session leader can not change its group anymore.
+	if c.cmd.SysProcAttr != nil {
+		c.cmd.SysProcAttr.Setpgid = false
+	}
+
+	ptmx, err := pty.StartWithSize(c.cmd, c.ttySize())
+	if err != nil {
+		return -1, err
+	}
+	c.startedAt = time.Now()
+	c.state = StateRunning
+	c.ptmx = ptmx
+	c.r = ptmx
+	c.w = nil
+	c.errR = nil
+	c.errW = nil
+	c.stdin = nil
+	if c.handleInput {
+		c.stdin = ptmx
+	}
+	c.markStarted()
+
+	return c.cmd.Process.Pid, nil
+}
+
+// ttySize is the size of the terminal of concur minus the prefix width.
+func (c *Command) ttySize() *pty.Winsize {
+	size, err := pty.GetsizeFull(os.Stdout)
+	if err != nil {
+		size = &pty.Winsize{Rows: 24, Cols: 80}
+	}
+	if cols := int(size.Cols) - c.prefixWidth; cols > 0 {
+		size.Cols = uint16(cols)
+	}
+	return size
+}
+
+// SetPrefixWidth sets the width of the prefix the output of the command is
+// shown with and resizes its pseudo-terminal accordingly.
+func (c *Command) SetPrefixWidth(width int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prefixWidth = width
+	c.resizeTTY()
+}
+
+// resizeTTY has to be called with c.mu held.
+func (c *Command) resizeTTY() {
+	if c.ptmx != nil {
+		_ = pty.Setsize(c.ptmx, c.ttySize())
+	}
+}
+
+// WatchTerminalSize resizes the pseudo-terminals of the commands whenever the
+// terminal of concur is resized, until the context is done.
+func WatchTerminalSize(ctx context.Context, commands []*Command) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGWINCH)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+			for _, c := range commands {
+				c.mu.Lock()
+				c.resizeTTY()
+				c.mu.Unlock()
+			}
+		}
+	}
+}
+
+// ttyReader ends the output of a pseudo-terminal with io.EOF instead of the
+// EIO Linux returns once the command closed its side.
+type ttyReader struct {
+	r io.Reader
+}
+
+func (t ttyReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if errors.Is(err, syscall.EIO) {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+func (c *Command) startTTY() (pid int, err error) {
+	return -1, errors.New("tty is not supported on windows")
+}
+
+func (c *Command) SetPrefixWidth(width int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prefixWidth = width
+}
+
+func WatchTerminalSize(context.Context, []*Command) {}
+
+type ttyReader struct {
+	r io.Reader
+}
+
+func (t ttyReader) Read(p []byte) (int, error) {
+	return t.r.Read(p)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/akatranlp/concur/internal/logger"
+)
+
+// Ready returns a channel that is closed once a line of the output matched
+// the readyWhen pattern of the command.
+func (c *Command) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// IsReady reports whether a line of the output matched the readyWhen pattern.
+func (c *Command) IsReady() bool {
+	select {
+	case <-c.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// matchReady checks a line of output against the readyWhen pattern and
+// reports true only for the first line that marks the command as ready.
+func (c *Command) matchReady(line string) bool {
+	if c.readyWhen == nil || c.IsReady() {
+		return false
+	}
+	if !c.readyWhen.MatchString(logger.StripANSI(line)) {
+		return false
+	}
+	var first bool
+	c.readyOnce.Do(func() {
+		first = true
+		close(c.ready)
+	})
+	return first
+}
+
+func (c *Command) waitReady(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.ready:
+		return nil
+	case <-c.finished:
+		if c.IsReady() {
+			return nil
+		}
+		return fmt.Errorf("%s exited before it became ready", c.displayName())
+	}
+}
+
+// readyWriter passes the raw output through and matches every complete
+// line against the readyWhen pattern of the command.
+type readyWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+	c   *Command
+	buf []byte
+}
+
+func (w *readyWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n, err := w.out.Write(p)
+	if w.c.IsReady() {
+		return n, err
+	}
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if w.c.matchReady(string(w.buf[:i])) {
+			fmt.Fprintf(w.c.out, "%s is ready\n", w.c.displayName())
+			w.buf = nil
+			break
+		}
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) > bufio.MaxScanTokenSize {
+		w.buf = nil
+	}
+	return n, err
+}
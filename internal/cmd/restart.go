@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/akatranlp/concur/internal/config"
+	"github.com/akatranlp/concur/internal/logger"
+)
+
+// SuperviseWithPrefix waits for the command and restarts it according to its
+// restart policy or when Restart is called. If the command was not started
+// with StartWithPrefix yet, it is started once its dependencies are met.
+// onStart is called with the pid of every process started here. The returned
+// error is the one of the last run.
+func (c *Command) SuperviseWithPrefix(id int, msgCh chan<- logger.Message, onStart func(pid int)) (err error) {
+	defer func() {
+		c.setState(StateExited)
+		c.finish(err)
+		msgCh <- logger.Message{ID: id, Event: &logger.Event{Type: logger.EventDone}}
+	}()
+
+	run := func() error {
+		pid, err := c.StartWithPrefix()
+		if err != nil {
+			msgCh <- logger.Message{ID: id, Text: fmt.Sprintf("%s failed to start: %s\n", c.cfg.Command, err)}
+			return err
+		}
+		onStart(pid)
+		msgCh <- startMessage(id, pid)
+		return c.WaitWithPrefix(id, msgCh)
+	}
+
+	if c.cmd != nil {
+		msgCh <- startMessage(id, c.cmd.Process.Pid)
+		err = c.WaitWithPrefix(id, msgCh)
+	} else {
+		if err := c.waitDependencies(); err != nil {
+			msgCh <- logger.Message{ID: id, Text: fmt.Sprintf("%s not started: %s\n", c.cfg.Command, err)}
+			return err
+		}
+		err = run()
+	}
+
+	attempt := 0
+	for {
+		switch c.takeRequest() {
+		case requestRestart:
+			attempt = 0
+		case requestStop:
+			c.setState(StateStopped)
+			msgCh <- logger.Message{ID: id, Text: fmt.Sprintf("%s stopped\n", c.cfg.Command)}
+			if !c.waitRequest() {
+				// Stopping is no failure of the command.
+				return nil
+			}
+			continue
+		default:
+			attempt++
+			if !c.shouldRestart(err, attempt) {
+				return err
+			}
+			delay := backoff(c.cfg.Restart, attempt)
+			c.setState(StateRestarting)
+			msgCh <- logger.Message{ID: id, Text: c.restartMessage(delay, attempt)}
+			if !c.sleep(delay) {
+				return err
+			}
+			if c.hasRequest() {
+				continue
+			}
+		}
+		err = run()
+	}
+}
+
+// SuperviseRaw runs the command in raw mode once its dependencies are met and
+// restarts it according to its restart policy. The returned error is the one
+// of the last run.
+func (c *Command) SuperviseRaw() (err error) {
+	defer func() { c.finish(err) }()
+
+	if err := c.waitDependencies(); err != nil {
+		fmt.Fprintf(c.out, "%s not started: %s\n", c.cfg.Command, err)
+		return err
+	}
+
+	err = c.RunRaw()
+	for attempt := 1; c.shouldRestart(err, attempt); attempt++ {
+		delay := backoff(c.cfg.Restart, attempt)
+		fmt.Fprint(c.out, c.restartMessage(delay, attempt))
+		if !c.sleep(delay) {
+			break
+		}
+		err = c.RunRaw()
+	}
+	return err
+}
+
+// startMessage is the message of the start event, which has no text.
+func startMessage(id, pid int) logger.Message {
+	return logger.Message{ID: id, Event: &logger.Event{Type: logger.EventStart, Pid: pid}}
+}
+
+func (c *Command) shouldRestart(err error, attempt int) bool {
+	if c.ctx.Err() != nil {
+		return false
+	}
+	restart := c.cfg.Restart
+	if restart.MaxAttempts > 0 && attempt > restart.MaxAttempts {
+		return false
+	}
+	switch restart.Policy {
+	case config.RestartPolicyAlways:
+		return true
+	case config.RestartPolicyOnFailure:
+		return err != nil
+	}
+	return false
+}
+
+func (c *Command) restartMessage(delay time.Duration, attempt int) string {
+	if c.cfg.Restart.MaxAttempts > 0 {
+		return fmt.Sprintf("restarting %s in %s (attempt %d/%d)\n", c.cfg.Command, delay.Round(time.Millisecond), attempt, c.cfg.Restart.MaxAttempts)
+	}
+	return fmt.Sprintf("restarting %s in %s (attempt %d)\n", c.cfg.Command, delay.Round(time.Millisecond), attempt)
+}
+
+// sleep waits for the given duration or until a request is made and reports
+// false if the command context was cancelled in the meantime.
+func (c *Command) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-c.ctx.Done():
+		return false
+	case <-c.requested:
+		return true
+	case <-timer.C:
+		return true
+	}
+}
+
+// backoff returns the exponential delay before the given restart attempt
+// with "equal jitter": half of the delay is fixed, the other half is random.
+func backoff(cfg config.RestartConfig, attempt int) time.Duration {
+	base := cfg.Backoff
+	if base == 0 {
+		base = config.DefaultRestartBackoff
+	}
+	limit := cfg.MaxBackoff
+	if limit == 0 {
+		limit = config.DefaultRestartMaxBackoff
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	delay = min(delay, limit)
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half)
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akatranlp/concur/internal/config"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.RestartConfig
+		attempt int
+		delay   time.Duration
+	}{
+		{name: "default first attempt", attempt: 1, delay: config.DefaultRestartBackoff},
+		{name: "default doubles", attempt: 3, delay: 4 * config.DefaultRestartBackoff},
+		{name: "default limit", attempt: 10, delay: config.DefaultRestartMaxBackoff},
+		{
+			name:    "first attempt",
+			cfg:     config.RestartConfig{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second},
+			attempt: 1,
+			delay:   100 * time.Millisecond,
+		},
+		{
+			name:    "doubles",
+			cfg:     config.RestartConfig{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second},
+			attempt: 4,
+			delay:   800 * time.Millisecond,
+		},
+		{
+			name:    "limit",
+			cfg:     config.RestartConfig{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second},
+			attempt: 5,
+			delay:   time.Second,
+		},
+		{
+			name:    "many attempts",
+			cfg:     config.RestartConfig{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second},
+			attempt: 1000,
+			delay:   time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Half of the delay is fixed, the other half is jitter.
+			for range 1000 {
+				got := backoff(tt.cfg, tt.attempt)
+				if got < tt.delay/2 || got >= tt.delay {
+					t.Fatalf("backoff = %s, want in [%s, %s)", got, tt.delay/2, tt.delay)
+				}
+			}
+		})
+	}
+}
+
+// countingCommand returns a command that appends a line to the file $f on
+// every run and exits with the given exit code, and a function returning
+// the number of runs so far.
+func countingCommand(t *testing.T, exit string) (string, func() int) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "runs")
+	command := "f='" + path + "'; echo run >> \"$f\"; exit " + exit
+	return command, func() int {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return 0
+		}
+		return strings.Count(string(b), "run")
+	}
+}
+
+func TestSuperviseRawRestarts(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  config.RestartPolicy
+		max     int
+		exit    string
+		runs    int
+		wantErr bool
+	}{
+		{name: "never", policy: config.RestartPolicyNever, exit: "1", runs: 1, wantErr: true},
+		{name: "on-failure succeeds", policy: config.RestartPolicyOnFailure, max: 3, exit: "0", runs: 1},
+		{name: "on-failure max attempts", policy: config.RestartPolicyOnFailure, max: 2, exit: "1", runs: 3, wantErr: true},
+		{name: "always max attempts", policy: config.RestartPolicyAlways, max: 2, exit: "0", runs: 3},
+		{
+			// Fails on the first two runs only.
+			name:   "on-failure recovers",
+			policy: config.RestartPolicyOnFailure,
+			max:    5,
+			exit:   "$(( $(wc -l < \"$f\") < 3 ))",
+			runs:   3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, runs := countingCommand(t, tt.exit)
+
+			c := NewCommand(context.Background(), config.RunCommandConfig{
+				Command: command,
+				Restart: config.RestartConfig{
+					Policy:      tt.policy,
+					MaxAttempts: tt.max,
+					Backoff:     time.Millisecond,
+				},
+			})
+			c.SetOutput(io.Discard)
+
+			// killOthersOnFail acts on the returned error, so a failure only
+			// counts once the retries ran out.
+			err := c.SuperviseRaw()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if got := runs(); got != tt.runs {
+				t.Errorf("runs = %d, want %d", got, tt.runs)
+			}
+		})
+	}
+}
+
+func TestSuperviseRawStopsRestartingOnCancel(t *testing.T) {
+	command, runs := countingCommand(t, "1")
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewCommand(ctx, config.RunCommandConfig{
+		Command: command,
+		Restart: config.RestartConfig{
+			Policy:  config.RestartPolicyAlways,
+			Backoff: time.Hour,
+		},
+	})
+	c.SetOutput(io.Discard)
+
+	done := make(chan error)
+	go func() { done <- c.SuperviseRaw() }()
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("SuperviseRaw did not return while waiting for the next attempt")
+	}
+	if got := runs(); got != 1 {
+		t.Errorf("runs = %d, want 1", got)
+	}
+}
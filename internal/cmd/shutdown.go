@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// shutdown is used as exec.Cmd.Cancel. It sends the first signal of the
+// shutdown sequence and escalates through the remaining ones in the
+// background until the process, and with process groups every process it
+// started, exited. The sequence always ends with SIGKILL.
+func (c *Command) shutdown(process *os.Process, exited <-chan struct{}) error {
+	steps := c.cfg.Shutdown.WithKill()
+	if runtime.GOOS == "windows" {
+		return process.Kill()
+	}
+
+	group := c.cfg.UseProcessGroup()
+	err := signalProcess(process, steps[0].Signal.Sys(), group)
+	go func() {
+		for i, step := range steps {
+			if i > 0 {
+				_ = signalProcess(process, step.Signal.Sys(), group)
+			}
+			if i == len(steps)-1 {
+				return
+			}
+			if c.waitExited(process, exited, step.Timeout) {
+				return
+			}
+		}
+	}()
+	return err
+}
+
+// waitExited waits up to the timeout for the process to exit and reports
+// whether it did. With process groups it also waits for the descendants,
+// which can outlive the shell.
+func (c *Command) waitExited(process *os.Process, exited <-chan struct{}, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	if !c.cfg.UseProcessGroup() {
+		select {
+		case <-exited:
+			return true
+		case <-timer.C:
+			return false
+		}
+	}
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return false
+		case <-ticker.C:
+			if !groupAlive(process) {
+				return true
+			}
+		}
+	}
+}
+
+// Kill kills the running process, with process groups including every
+// process it started.
+func (c *Command) Kill() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cmd == nil || c.cmd.Process == nil {
+		return nil
+	}
+	return signalProcess(c.cmd.Process, syscall.SIGKILL, c.cfg.UseProcessGroup())
+}
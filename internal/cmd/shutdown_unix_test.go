@@ -0,0 +1,186 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/akatranlp/concur/internal/config"
+	"github.com/akatranlp/concur/internal/logger"
+)
+
+// startGroup starts the command in its own process group and returns the
+// process group id.
+func startGroup(t *testing.T, ctx context.Context, command string, shutdown config.ShutdownConfig) (*Command, int) {
+	t.Helper()
+	group := true
+	c := NewCommand(ctx, config.RunCommandConfig{
+		Command:      command,
+		ProcessGroup: &group,
+		Shutdown:     shutdown,
+	})
+	c.SetOutput(io.Discard)
+	if err := c.StartRaw(); err != nil {
+		t.Fatal(err)
+	}
+	pgid := c.Pid()
+	t.Cleanup(func() { _ = syscall.Kill(-pgid, syscall.SIGKILL) })
+	// Gives the shell time to start its background job.
+	time.Sleep(200 * time.Millisecond)
+	return c, pgid
+}
+
+// waitGroupGone waits until no process of the group is left. Orphaned
+// processes are only gone once init reaped them.
+func waitGroupGone(t *testing.T, pgid int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		err := syscall.Kill(-pgid, 0)
+		if errors.Is(err, syscall.ESRCH) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("process group %d still exists: kill(-pgid, 0) = %v", pgid, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestShutdownKillsDescendants(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, pgid := startGroup(t, ctx, "sleep 1000 & sleep 1000", config.ShutdownConfig{
+		{Signal: config.KillSignal(syscall.SIGTERM), Timeout: 5 * time.Second},
+		{Signal: config.KillSignal(syscall.SIGKILL)},
+	})
+
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		_ = c.wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("command did not exit after SIGTERM")
+	}
+	waitGroupGone(t, pgid, 5*time.Second)
+}
+
+func TestShutdownEscalatesToSIGKILL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The ignored SIGTERM is inherited by the background job.
+	c, pgid := startGroup(t, ctx, "trap '' TERM; sleep 1000 & sleep 1000", config.ShutdownConfig{
+		{Signal: config.KillSignal(syscall.SIGTERM), Timeout: 500 * time.Millisecond},
+		{Signal: config.KillSignal(syscall.SIGKILL)},
+	})
+
+	start := time.Now()
+	cancel()
+	go func() { _ = c.wait() }()
+
+	time.Sleep(250 * time.Millisecond)
+	if err := syscall.Kill(-pgid, 0); err != nil {
+		t.Fatalf("process group exited before the shutdown timeout: %v", err)
+	}
+	waitGroupGone(t, pgid, 5*time.Second)
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("process group exited after %s, before the shutdown timeout", elapsed)
+	}
+}
+
+func TestShutdownEndsWithSIGKILL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCommand(ctx, config.RunCommandConfig{
+		Command: "trap '' HUP INT QUIT TERM USR1 USR2; while :; do sleep 0.1; done",
+		// Without SIGKILL at the end, it is sent after the last timeout.
+		Shutdown: config.ShutdownConfig{
+			{Signal: config.KillSignal(syscall.SIGINT), Timeout: 300 * time.Millisecond},
+			{Signal: config.KillSignal(syscall.SIGTERM), Timeout: 300 * time.Millisecond},
+		},
+	})
+	c.SetOutput(io.Discard)
+	if err := c.StartRaw(); err != nil {
+		t.Fatal(err)
+	}
+	// Gives the shell time to set up the traps.
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		_ = c.wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("command ignoring every signal but SIGKILL did not exit")
+	}
+	if elapsed := time.Since(start); elapsed < 600*time.Millisecond {
+		t.Errorf("command exited after %s, before the timeouts of the sequence", elapsed)
+	}
+}
+
+func TestShellExitsFirst(t *testing.T) {
+	group := true
+	cfg := config.RunCommandConfig{
+		// The background job holds the output open after the shell exited.
+		Command:      "sleep 1000 & echo started",
+		ProcessGroup: &group,
+	}
+
+	t.Run("prefix", func(t *testing.T) {
+		c := NewCommand(context.Background(), cfg)
+		pid, err := c.StartWithPrefix()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = syscall.Kill(-pid, syscall.SIGKILL) })
+
+		msgCh := make(chan logger.Message, 10)
+		done := make(chan error)
+		go func() { done <- c.WaitWithPrefix(0, msgCh) }()
+		select {
+		case <-done:
+		case <-time.After(3 * time.Second):
+			t.Fatal("WaitWithPrefix did not return after the shell exited")
+		}
+		waitGroupGone(t, pid, 5*time.Second)
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		c := NewCommand(context.Background(), cfg)
+		// Output that is no file is copied by concur.
+		var out strings.Builder
+		c.SetOutput(&out)
+		if err := c.StartRaw(); err != nil {
+			t.Fatal(err)
+		}
+		pid := c.Pid()
+		t.Cleanup(func() { _ = syscall.Kill(-pid, syscall.SIGKILL) })
+
+		done := make(chan error)
+		go func() { done <- c.wait() }()
+		select {
+		case <-done:
+		case <-time.After(3 * time.Second):
+			t.Fatal("wait did not return after the shell exited")
+		}
+		waitGroupGone(t, pid, 5*time.Second)
+		if out.String() != "started\n" {
+			t.Errorf("output = %q, want %q", out.String(), "started\n")
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"syscall"
+
+	"github.com/akatranlp/concur/internal/config"
+)
+
+// Result is the outcome of a concurrently run command.
+type Result struct {
+	Index int
+	Name  string
+	Err   error
+}
+
+// ExitError carries the exit code concur should exit with.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of a command error. Commands killed by a
+// signal get 128 + signal like in a shell, any other error is 1.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 1
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	if code := exitErr.ExitCode(); code > 0 {
+		return code
+	}
+	return 1
+}
+
+// Decide picks the results that decide the exit code according to the
+// success condition. The results have to be in completion order. It returns
+// an *ExitError for the first deciding result that failed, otherwise nil.
+func Decide(cond config.SuccessCondition, results []Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	var deciding []Result
+	switch cond {
+	case config.SuccessConditionAll, "":
+		deciding = results
+	case config.SuccessConditionFirst:
+		deciding = results[:1]
+	case config.SuccessConditionLast:
+		deciding = results[len(results)-1:]
+	default:
+		_, negated := cond.Command()
+		for _, result := range results {
+			if cond.Matches(result.Index, result.Name) != negated {
+				deciding = append(deciding, result)
+			}
+		}
+	}
+
+	for _, result := range deciding {
+		if result.Err != nil {
+			name := result.Name
+			if name == "" {
+				name = strconv.Itoa(result.Index)
+			}
+			return &ExitError{
+				Code: ExitCode(result.Err),
+				Err:  fmt.Errorf("command %s failed: %w", name, result.Err),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// AssertConfig decides whether a single check passed. Without assertions
+// http checks pass with 2xx and 3xx status codes and command checks with
+// exit code 0.
+type AssertConfig struct {
+	StatusCodes []int              `mapstructure:"statusCodes"`
+	Body        string             `mapstructure:"body"`
+	JSON        []JSONAssertConfig `mapstructure:"json"`
+	ExitCode    int                `mapstructure:"exitCode"`
+}
+
+// JSONAssertConfig expects the value at a path like checks.db.status to
+// equal Equals.
+type JSONAssertConfig struct {
+	Path   string `mapstructure:"path"`
+	Equals any    `mapstructure:"equals"`
+}
+
+func (c AssertConfig) Validate(t CheckType) error {
+	if len(c.StatusCodes) > 0 && t != CheckTypeHTTP {
+		return errors.New("assert.statusCodes is only supported by http checks")
+	}
+	for _, code := range c.StatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("invalid status code: %d", code)
+		}
+	}
+	if c.Body != "" {
+		if t != CheckTypeHTTP && t != CheckTypeCommand {
+			return errors.New("assert.body is only supported by http and command checks")
+		}
+		if _, err := regexp.Compile(c.Body); err != nil {
+			return fmt.Errorf("assert.body: %w", err)
+		}
+	}
+	if len(c.JSON) > 0 && t != CheckTypeHTTP {
+		return errors.New("assert.json is only supported by http checks")
+	}
+	for _, assertion := range c.JSON {
+		if assertion.Path == "" {
+			return errors.New("assert.json: empty path")
+		}
+	}
+	if c.ExitCode != 0 && t != CheckTypeCommand {
+		return errors.New("assert.exitCode is only supported by command checks")
+	}
+	return nil
+}
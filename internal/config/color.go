@@ -25,10 +25,28 @@ var colorMap = map[string]string{
 	"hiwhite":   "97",
 }
 
+// colorsEnabled is false when concur must not write colors.
+var colorsEnabled = true
+
+// SetColorsEnabled sets whether colors are written at all.
+func SetColorsEnabled(enabled bool) {
+	colorsEnabled = enabled
+}
+
+// ColorsEnabled reports whether colors are written at all.
+func ColorsEnabled() bool {
+	return colorsEnabled
+}
+
 type Color struct {
 	segments string
 }
 
+// IsSet reports whether a color was set.
+func (c Color) IsSet() bool {
+	return c.segments != ""
+}
+
 func (c Color) Validate() error {
 	return nil
 }
@@ -110,7 +128,15 @@ func (c Sequence) Validate() error {
 	return c.Color.Validate()
 }
 
+// IsSet reports whether any color or style is set.
+func (c Sequence) IsSet() bool {
+	return c.Color.IsSet() || c.Bold || c.Underline
+}
+
 func (c Sequence) Apply(str string) string {
+	if !colorsEnabled {
+		return str
+	}
 	sequence := c.Color.segments
 	if c.Bold {
 		sequence += ";1"
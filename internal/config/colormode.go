@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ColorMode decides whether concur writes colors.
+type ColorMode string
+
+func (m ColorMode) Validate() error {
+	switch m {
+	case ColorModeAuto, ColorModeAlways, ColorModeNever, "":
+		return nil
+	}
+	return fmt.Errorf("invalid color mode: %s", m)
+}
+
+const (
+	ColorModeAuto   ColorMode = "auto"
+	ColorModeAlways ColorMode = "always"
+	ColorModeNever  ColorMode = "never"
+)
+
+// Enabled reports whether colors are written to out. In auto mode NO_COLOR
+// disables and FORCE_COLOR enables them, otherwise colors are only written
+// to a terminal.
+func (m ColorMode) Enabled(out *os.File) bool {
+	switch m {
+	case ColorModeAlways:
+		return true
+	case ColorModeNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	switch os.Getenv("FORCE_COLOR") {
+	case "", "0", "false":
+	default:
+		return true
+	}
+	return term.IsTerminal(int(out.Fd()))
+}
+
+// AutoColor decides which color a command without a color gets.
+type AutoColor string
+
+func (a AutoColor) Validate() error {
+	switch a {
+	case AutoColorNone, AutoColorPalette, AutoColorHash, "":
+		return nil
+	}
+	return fmt.Errorf("invalid auto color: %s", a)
+}
+
+const (
+	// AutoColorNone leaves the prefix uncolored.
+	AutoColorNone AutoColor = "none"
+	// AutoColorPalette picks the colors of the palette in order.
+	AutoColorPalette AutoColor = "palette"
+	// AutoColorHash picks a color by the hash of the name, so that a command
+	// keeps its color between runs.
+	AutoColorHash AutoColor = "hash"
+)
+
+var palette = []string{"cyan", "magenta", "yellow", "green", "blue", "red", "hicyan", "himagenta", "hiyellow", "higreen", "hiblue", "hired"}
+
+// Pick returns the automatic color of the command at the given index.
+func (a AutoColor) Pick(idx int, command RunCommandConfig) (Color, bool) {
+	var name string
+	switch a {
+	case AutoColorPalette:
+		name = palette[idx%len(palette)]
+	case AutoColorHash:
+		key := command.Name
+		if key == "" {
+			key = command.Command
+		}
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		name = palette[h.Sum32()%uint32(len(palette))]
+	default:
+		return Color{}, false
+	}
+	return Color{segments: colorMap[name]}, true
+}
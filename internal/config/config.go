@@ -3,7 +3,10 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/mitchellh/mapstructure"
@@ -15,25 +18,137 @@ var (
 )
 
 type RunCommandConfig struct {
-	Command     string   `mapstructure:"command"`
-	Name        string   `mapstructure:"name"`
-	PrefixColor Sequence `mapstructure:",squash"`
-	CWD         string   `mapstructure:"cwd"`
-	Debug       bool     `mapstructure:"debug"`
+	Command      string             `mapstructure:"command"`
+	Name         string             `mapstructure:"name"`
+	PrefixColor  Sequence           `mapstructure:",squash"`
+	CWD          string             `mapstructure:"cwd"`
+	Debug        bool               `mapstructure:"debug"`
+	Restart      RestartConfig      `mapstructure:"restart"`
+	DependsOn    []DependencyConfig `mapstructure:"dependsOn"`
+	HealthCheck  *StatusCheckConfig `mapstructure:"healthCheck"`
+	ReadyWhen    string             `mapstructure:"readyWhen"`
+	Shutdown     ShutdownConfig     `mapstructure:"shutdown"`
+	ProcessGroup *bool              `mapstructure:"processGroup"`
+	Env          map[string]string  `mapstructure:"env"`
+	EnvFile      []string           `mapstructure:"envFile"`
+	TTY          bool               `mapstructure:"tty"`
+	LogFile      string             `mapstructure:"logFile"`
 }
 
 func (c RunCommandConfig) Validate() error {
 	if c.Command == "" {
 		return ErrEmptyCommand
 	}
+	if err := c.Restart.Validate(); err != nil {
+		return err
+	}
+	for _, dep := range c.DependsOn {
+		if err := dep.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.HealthCheck != nil {
+		if err := c.HealthCheck.Validate(); err != nil {
+			return err
+		}
+	}
+	if _, err := regexp.Compile(c.ReadyWhen); err != nil {
+		return fmt.Errorf("invalid readyWhen pattern: %w", err)
+	}
+	if err := c.Shutdown.Validate(); err != nil {
+		return err
+	}
 	return c.PrefixColor.Validate()
 }
 
+// UseProcessGroup reports whether the command runs in its own process group,
+// which is the default.
+func (c RunCommandConfig) UseProcessGroup() bool {
+	return c.ProcessGroup == nil || *c.ProcessGroup
+}
+
+type DependencyCondition string
+
+func (d DependencyCondition) Validate() error {
+	switch d {
+	case DependencyConditionStarted, DependencyConditionReady, DependencyConditionHealthy, DependencyConditionCompleted, "":
+		return nil
+	}
+	return fmt.Errorf("invalid dependency condition: %s", d)
+}
+
+const (
+	DependencyConditionStarted   DependencyCondition = "started"
+	DependencyConditionReady     DependencyCondition = "ready"
+	DependencyConditionHealthy   DependencyCondition = "healthy"
+	DependencyConditionCompleted DependencyCondition = "completed"
+)
+
+type DependencyConfig struct {
+	Name      string              `mapstructure:"name"`
+	Condition DependencyCondition `mapstructure:"condition"`
+}
+
+func (c DependencyConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("empty dependency name")
+	}
+	return c.Condition.Validate()
+}
+
+// GetCondition returns the condition of the dependency, defaulting to started.
+func (c DependencyConfig) GetCondition() DependencyCondition {
+	if c.Condition == "" {
+		return DependencyConditionStarted
+	}
+	return c.Condition
+}
+
+type RestartPolicy string
+
+func (r RestartPolicy) Validate() error {
+	switch r {
+	case RestartPolicyNever, RestartPolicyOnFailure, RestartPolicyAlways, "":
+		return nil
+	}
+	return fmt.Errorf("invalid restart policy: %s", r)
+}
+
+const (
+	RestartPolicyNever     RestartPolicy = "never"
+	RestartPolicyOnFailure RestartPolicy = "on-failure"
+	RestartPolicyAlways    RestartPolicy = "always"
+)
+
+const (
+	DefaultRestartBackoff    = time.Second
+	DefaultRestartMaxBackoff = 30 * time.Second
+)
+
+type RestartConfig struct {
+	Policy      RestartPolicy `mapstructure:"policy"`
+	MaxAttempts int           `mapstructure:"maxAttempts"`
+	Backoff     time.Duration `mapstructure:"backoff"`
+	MaxBackoff  time.Duration `mapstructure:"maxBackoff"`
+}
+
+func (c RestartConfig) Validate() error {
+	if err := c.Policy.Validate(); err != nil {
+		return err
+	}
+	if c.MaxAttempts < 0 {
+		return errors.New("restart max attempts must not be negative")
+	} else if c.Backoff < 0 || c.MaxBackoff < 0 {
+		return errors.New("restart backoff must not be negative")
+	}
+	return nil
+}
+
 type InputType string
 
 func (i InputType) Validate() error {
 	switch i {
-	case InputTypeStdin, InputTypePrevious, InputTypeNone:
+	case InputTypeStdin, InputTypePrevious, InputTypeNone, "":
 		return nil
 	}
 	return errors.New("invalid input type")
@@ -49,10 +164,10 @@ type OutputType string
 
 func (i OutputType) Validate() error {
 	switch i {
-	case OutputTypeStdout, OutputTypePrevious, OutputTypeNone:
+	case OutputTypeStdout, OutputTypePrevious, OutputTypeNone, "":
 		return nil
 	}
-	return errors.New("invalid input type")
+	return errors.New("invalid output type")
 }
 
 const (
@@ -61,14 +176,87 @@ const (
 	OutputTypeNone     OutputType = "none"
 )
 
+// OutputFormat is the format the output of the concurrently run commands is
+// written in.
+type OutputFormat string
+
+func (f OutputFormat) Validate() error {
+	switch f {
+	case OutputFormatText, OutputFormatJSON, "":
+		return nil
+	}
+	return fmt.Errorf("invalid output format: %s", f)
+}
+
+const (
+	OutputFormatText OutputFormat = "text"
+	OutputFormatJSON OutputFormat = "json"
+)
+
+// GroupOrder is the order in which the buffered output of the commands is
+// printed in group mode.
+type GroupOrder string
+
+func (o GroupOrder) Validate() error {
+	switch o {
+	case GroupOrderConfig, GroupOrderCompletion, "":
+		return nil
+	}
+	return fmt.Errorf("invalid group order: %s", o)
+}
+
+const (
+	GroupOrderConfig     GroupOrder = "config"
+	GroupOrderCompletion GroupOrder = "completion"
+)
+
+// PipelineConfig connects a sequentially run command to the previous and
+// next one. Input defaults to none and output to stdout. Output previous
+// passes the output to the next command, which reads it with input previous.
+type PipelineConfig struct {
+	Input  InputType  `mapstructure:"input"`
+	Output OutputType `mapstructure:"output"`
+}
+
+func (c PipelineConfig) Validate() error {
+	if err := c.Input.Validate(); err != nil {
+		return err
+	}
+	return c.Output.Validate()
+}
+
+// validatePipeline checks that every command reading the previous output
+// follows a command that passes its output on and vice versa.
+func validatePipeline(pipeline []PipelineConfig) error {
+	for i, step := range pipeline {
+		if err := step.Validate(); err != nil {
+			return err
+		}
+		passesOutput := i > 0 && pipeline[i-1].Output == OutputTypePrevious
+		if step.Input == InputTypePrevious && !passesOutput {
+			return fmt.Errorf("command %d reads the previous output, but the previous command does not pass its output on", i)
+		} else if step.Input != InputTypePrevious && passesOutput {
+			return fmt.Errorf("command %d passes its output on, but the next command does not read it", i-1)
+		}
+	}
+	if n := len(pipeline); n > 0 && pipeline[n-1].Output == OutputTypePrevious {
+		return fmt.Errorf("command %d passes its output on, but there is no next command", n-1)
+	}
+	return nil
+}
+
 type RunBeforeCommandConfig struct {
 	RunCommandConfig `mapstructure:",squash"`
+	PipelineConfig   `mapstructure:",squash"`
 }
 
 func (c RunBeforeCommandConfig) Validate() error {
 	if err := c.RunCommandConfig.Validate(); err != nil {
 		return err
 	}
+	if err := c.PipelineConfig.Validate(); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -78,23 +266,26 @@ type RunBeforeConfig struct {
 }
 
 func (c RunBeforeConfig) Validate() error {
-	for _, command := range c.Commands {
+	pipeline := make([]PipelineConfig, len(c.Commands))
+	for i, command := range c.Commands {
 		if err := command.Validate(); err != nil {
 			return err
 		}
+		pipeline[i] = command.PipelineConfig
 	}
-	return nil
+	return validatePipeline(pipeline)
 }
 
 type RunAfterCommandConfig struct {
 	RunCommandConfig `mapstructure:",squash"`
+	PipelineConfig   `mapstructure:",squash"`
 }
 
 func (c RunAfterCommandConfig) Validate() error {
 	if err := c.RunCommandConfig.Validate(); err != nil {
 		return err
 	}
-	return nil
+	return c.PipelineConfig.Validate()
 }
 
 type RunAfterConfig struct {
@@ -102,20 +293,30 @@ type RunAfterConfig struct {
 }
 
 func (c RunAfterConfig) Validate() error {
-	for _, command := range c.Commands {
+	pipeline := make([]PipelineConfig, len(c.Commands))
+	for i, command := range c.Commands {
 		if err := command.Validate(); err != nil {
 			return err
 		}
+		pipeline[i] = command.PipelineConfig
 	}
-	return nil
+	return validatePipeline(pipeline)
 }
 
 type PrefixConfig struct {
-	Template        string `mapstructure:"template"`
-	PadPrefix       bool   `mapstructure:"padPrefix"`
-	PrefixLength    int    `mapstructure:"prefixLength"`
-	TimestampFormat string `mapstructure:"timestampFormat"`
-	TimeSinceStart  bool   `mapstructure:"timeSinceStart"`
+	Template        string       `mapstructure:"template"`
+	PadPrefix       bool         `mapstructure:"padPrefix"`
+	PrefixLength    int          `mapstructure:"prefixLength"`
+	TimestampFormat string       `mapstructure:"timestampFormat"`
+	TimeSinceStart  bool         `mapstructure:"timeSinceStart"`
+	Stderr          StderrConfig `mapstructure:"stderr"`
+	AutoColor       AutoColor    `mapstructure:"autoColor"`
+}
+
+// StderrConfig sets how the prefix of lines a command wrote to stderr looks.
+type StderrConfig struct {
+	Sequence Sequence `mapstructure:",squash"`
+	Marker   string   `mapstructure:"marker"`
 }
 
 type CheckType string
@@ -123,14 +324,49 @@ type CheckType string
 const (
 	CheckTypeCommand CheckType = "command"
 	CheckTypeHTTP    CheckType = "http"
+	CheckTypeTCP     CheckType = "tcp"
+	CheckTypeGRPC    CheckType = "grpc"
 )
 
+type RedirectPolicy string
+
+func (r RedirectPolicy) Validate() error {
+	switch r {
+	case RedirectPolicyFollow, RedirectPolicyNone, "":
+		return nil
+	}
+	return fmt.Errorf("invalid redirect policy: %s", r)
+}
+
+const (
+	RedirectPolicyFollow RedirectPolicy = "follow"
+	RedirectPolicyNone   RedirectPolicy = "none"
+)
+
+const DefaultCheckInterval = 2 * time.Second
+
 type StatusCheckConfig struct {
 	Type     CheckType     `mapstructure:"type"`
 	Interval time.Duration `mapstructure:"interval"`
 	Command  string        `mapstructure:"command"`
 	URL      string        `mapstructure:"url"`
+	Address  string        `mapstructure:"address"`
+	Send     string        `mapstructure:"send"`
+	Expect   string        `mapstructure:"expect"`
+	Timeout  time.Duration `mapstructure:"timeout"`
 	Template string        `mapstructure:"template"`
+
+	Method    string            `mapstructure:"method"`
+	Body      string            `mapstructure:"body"`
+	Redirects RedirectPolicy    `mapstructure:"redirects"`
+	Service   string            `mapstructure:"service"`
+	Headers   map[string]string `mapstructure:"headers"`
+	TLS       TLSConfig         `mapstructure:"tls"`
+
+	HealthyThreshold   int           `mapstructure:"healthyThreshold"`
+	UnhealthyThreshold int           `mapstructure:"unhealthyThreshold"`
+	StartPeriod        time.Duration `mapstructure:"startPeriod"`
+	Assert             AssertConfig  `mapstructure:"assert"`
 }
 
 func (c StatusCheckConfig) Validate() error {
@@ -138,6 +374,8 @@ func (c StatusCheckConfig) Validate() error {
 	case CheckTypeCommand:
 		if c.Command == "" {
 			return ErrEmptyCommand
+		} else if c.Interval != 0 && c.Interval <= 100*time.Millisecond {
+			return errors.New("interval too small")
 		}
 	case CheckTypeHTTP:
 		if c.URL == "" {
@@ -146,13 +384,55 @@ func (c StatusCheckConfig) Validate() error {
 			return err
 		} else if c.Template == "" {
 			return errors.New("empty template")
-		} else if c.Interval <= 100*time.Millisecond {
+		} else if c.Interval != 0 && c.Interval <= 100*time.Millisecond {
 			return errors.New("interval too small")
+		} else if c.Timeout < 0 {
+			return errors.New("invalid timeout")
+		} else if err := c.Redirects.Validate(); err != nil {
+			return err
+		} else if err := c.TLS.Validate(); err != nil {
+			return err
+		}
+	case CheckTypeTCP:
+		if c.Address == "" {
+			return errors.New("empty address")
+		} else if _, _, err := net.SplitHostPort(c.Address); err != nil {
+			return err
+		} else if c.Interval != 0 && c.Interval <= 100*time.Millisecond {
+			return errors.New("interval too small")
+		} else if c.Timeout < 0 {
+			return errors.New("invalid timeout")
+		}
+	case CheckTypeGRPC:
+		if c.Address == "" {
+			return errors.New("empty address")
+		} else if c.Interval != 0 && c.Interval <= 100*time.Millisecond {
+			return errors.New("interval too small")
+		} else if c.Timeout < 0 {
+			return errors.New("invalid timeout")
+		} else if err := c.TLS.Validate(); err != nil {
+			return err
 		}
 	default:
 		return errors.New("invalid check type")
 	}
-	return nil
+	if c.HealthyThreshold < 0 || c.UnhealthyThreshold < 0 {
+		return errors.New("invalid threshold")
+	} else if c.StartPeriod < 0 {
+		return errors.New("invalid start period")
+	}
+	return c.Assert.Validate(c.Type)
+}
+
+// applyDefaults sets the interval and the timeout, which defaults to half the
+// interval, if they are not set.
+func (c *StatusCheckConfig) applyDefaults() {
+	if c.Interval == 0 {
+		c.Interval = DefaultCheckInterval
+	}
+	if c.Timeout == 0 && c.Type != CheckTypeCommand {
+		c.Timeout = c.Interval / 2
+	}
 }
 
 type StatusConfig struct {
@@ -181,16 +461,34 @@ func (c StatusConfig) Validate() error {
 }
 
 type Config struct {
-	Raw              bool         `mapstructure:"raw"`
-	KillOthers       bool         `mapstructure:"killOthers"`
-	KillOthersOnFail bool         `mapstructure:"killOthersOnFail"`
-	KillSignal       KillSignal   `mapstructure:"killSignal"`
-	Debug            bool         `mapstructure:"debug"`
-	Prefix           PrefixConfig `mapstructure:"prefix"`
-	Commands         []RunCommandConfig
-	Status           StatusConfig
-	RunBefore        RunBeforeConfig
-	RunAfter         RunAfterConfig
+	Raw                bool              `mapstructure:"raw"`
+	KillOthers         bool              `mapstructure:"killOthers"`
+	KillOthersOnFail   bool              `mapstructure:"killOthersOnFail"`
+	KillSignal         KillSignal        `mapstructure:"killSignal"`
+	Shutdown           ShutdownConfig    `mapstructure:"shutdown"`
+	ProcessGroup       bool              `mapstructure:"processGroup"`
+	Success            SuccessCondition  `mapstructure:"success"`
+	Output             OutputFormat      `mapstructure:"output"`
+	ColorMode          ColorMode         `mapstructure:"colorMode"`
+	Group              bool              `mapstructure:"group"`
+	TUI                bool              `mapstructure:"tui"`
+	Keyboard           bool              `mapstructure:"keyboard"`
+	GroupOrder         GroupOrder        `mapstructure:"groupOrder"`
+	StderrOnly         bool              `mapstructure:"stderrOnly"`
+	LogDir             string            `mapstructure:"logDir"`
+	Log                LogConfig         `mapstructure:"log"`
+	Control            ControlConfig     `mapstructure:"control"`
+	WaitFor            WaitForConfig     `mapstructure:"waitFor"`
+	HandleInput        bool              `mapstructure:"handleInput"`
+	DefaultInputTarget string            `mapstructure:"defaultInputTarget"`
+	Env                map[string]string `mapstructure:"env"`
+	EnvFile            []string          `mapstructure:"envFile"`
+	Debug              bool              `mapstructure:"debug"`
+	Prefix             PrefixConfig      `mapstructure:"prefix"`
+	Commands           []RunCommandConfig
+	Status             StatusConfig
+	RunBefore          RunBeforeConfig
+	RunAfter           RunAfterConfig
 }
 
 func (c Config) Validate() error {
@@ -199,6 +497,9 @@ func (c Config) Validate() error {
 			return err
 		}
 	}
+	if err := c.validateDependencies(); err != nil {
+		return err
+	}
 	if err := c.RunBefore.Validate(); err != nil {
 		return err
 	}
@@ -208,10 +509,235 @@ func (c Config) Validate() error {
 	if err := c.Status.Validate(); err != nil {
 		return err
 	}
+	if err := c.Shutdown.Validate(); err != nil {
+		return err
+	}
+	if err := c.validateSuccess(); err != nil {
+		return err
+	}
+	if err := c.validateInput(); err != nil {
+		return err
+	}
+	if err := c.Output.Validate(); err != nil {
+		return err
+	} else if c.Output == OutputFormatJSON && c.Raw {
+		return errors.New("json output is not supported in raw mode")
+	}
+	if err := c.ColorMode.Validate(); err != nil {
+		return err
+	}
+	if err := c.Prefix.AutoColor.Validate(); err != nil {
+		return err
+	}
+	if err := c.GroupOrder.Validate(); err != nil {
+		return err
+	} else if c.Group && c.Raw {
+		return errors.New("group is not supported in raw mode")
+	}
+	if err := c.validateTUI(); err != nil {
+		return err
+	}
+	if err := c.validateKeyboard(); err != nil {
+		return err
+	}
+	if err := c.validateControl(); err != nil {
+		return err
+	}
+	if err := c.validateWaitFor(); err != nil {
+		return err
+	}
+	if err := c.Log.Validate(); err != nil {
+		return err
+	}
+	if err := c.validateLogs(); err != nil {
+		return err
+	}
 
 	return nil
 }
 
+func (c Config) validateSuccess() error {
+	if err := c.Success.Validate(); err != nil {
+		return err
+	}
+	target, _ := c.Success.Command()
+	if target == "" {
+		return nil
+	}
+	for i, command := range c.Commands {
+		if c.Success.Matches(i, command.Name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("success condition %s refers to unknown command %q", c.Success, target)
+}
+
+func (c Config) validateTUI() error {
+	switch {
+	case !c.TUI:
+		return nil
+	case c.Raw:
+		return errors.New("tui is not supported in raw mode")
+	case c.Output == OutputFormatJSON:
+		return errors.New("tui is not supported with json output")
+	case c.Group:
+		return errors.New("tui is not supported in group mode")
+	case c.HandleInput:
+		return errors.New("tui is not supported with handleInput")
+	}
+	return nil
+}
+
+func (c Config) validateKeyboard() error {
+	switch {
+	case !c.Keyboard:
+		return nil
+	case c.Raw:
+		return errors.New("keyboard is not supported in raw mode")
+	case c.TUI:
+		return errors.New("keyboard is not supported with tui, it has its own keys")
+	case c.Output == OutputFormatJSON:
+		return errors.New("keyboard is not supported with json output")
+	case c.Group:
+		return errors.New("keyboard is not supported in group mode")
+	case c.HandleInput:
+		return errors.New("keyboard is not supported with handleInput")
+	}
+	return nil
+}
+
+func (c Config) validateInput() error {
+	if !c.HandleInput {
+		return nil
+	} else if c.Raw {
+		return errors.New("handleInput is not supported in raw mode")
+	}
+	for i, command := range c.Commands {
+		if MatchesCommand(c.DefaultInputTarget, i, command.Name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("default input target refers to unknown command %q", c.DefaultInputTarget)
+}
+
+// applyDefaults fills the per command settings that are not set with the
+// global ones.
+func (c *Config) applyDefaults() {
+	if len(c.Shutdown) == 0 {
+		c.Shutdown = defaultShutdown(c.KillSignal)
+	}
+
+	apply := func(command *RunCommandConfig) {
+		if len(command.Shutdown) == 0 {
+			command.Shutdown = c.Shutdown
+		}
+		if command.ProcessGroup == nil {
+			command.ProcessGroup = &c.ProcessGroup
+		}
+		if command.HealthCheck != nil {
+			command.HealthCheck.applyDefaults()
+		}
+	}
+	for i := range c.Commands {
+		apply(&c.Commands[i])
+		if c.Commands[i].PrefixColor.Color.IsSet() {
+			continue
+		}
+		if color, ok := c.Prefix.AutoColor.Pick(i, c.Commands[i]); ok {
+			c.Commands[i].PrefixColor.Color = color
+		}
+	}
+	for i := range c.RunBefore.Commands {
+		apply(&c.RunBefore.Commands[i].RunCommandConfig)
+	}
+	for i := range c.RunAfter.Commands {
+		apply(&c.RunAfter.Commands[i].RunCommandConfig)
+	}
+	// Status checks are also used by waitFor when the status is not shown.
+	for i := range c.Status.Checks {
+		c.Status.Checks[i].applyDefaults()
+	}
+	for i := range c.WaitFor.Checks {
+		c.WaitFor.Checks[i].applyDefaults()
+	}
+}
+
+// validateDependencies checks that every dependency refers to an existing
+// command that can reach the condition and that there are no cycles.
+func (c Config) validateDependencies() error {
+	byName := make(map[string]int, len(c.Commands))
+	duplicates := make(map[string]bool)
+	for i, command := range c.Commands {
+		if command.Name == "" {
+			continue
+		}
+		if _, ok := byName[command.Name]; ok {
+			duplicates[command.Name] = true
+		}
+		byName[command.Name] = i
+	}
+
+	for i, command := range c.Commands {
+		name := c.commandName(i)
+		for _, dep := range command.DependsOn {
+			idx, ok := byName[dep.Name]
+			if !ok {
+				return fmt.Errorf("command %q depends on unknown command %q", name, dep.Name)
+			} else if duplicates[dep.Name] {
+				return fmt.Errorf("command %q depends on ambiguous command name %q", name, dep.Name)
+			}
+			if dep.GetCondition() == DependencyConditionHealthy && c.Commands[idx].HealthCheck == nil {
+				return fmt.Errorf("command %q depends on %q being healthy, but it has no healthCheck", name, dep.Name)
+			} else if dep.GetCondition() == DependencyConditionReady && c.Commands[idx].ReadyWhen == "" {
+				return fmt.Errorf("command %q depends on %q being ready, but it has no readyWhen", name, dep.Name)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(c.Commands))
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		path = append(path, c.commandName(i))
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(path, " -> "))
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		for _, dep := range c.Commands[i].DependsOn {
+			if err := visit(byName[dep.Name], path); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		return nil
+	}
+	for i := range c.Commands {
+		if err := visit(i, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commandName returns the name of the command at index i for messages,
+// which falls back to the command itself or its index.
+func (c Config) commandName(i int) string {
+	if name := c.Commands[i].Name; name != "" {
+		return name
+	}
+	if command := c.Commands[i].Command; command != "" {
+		return command
+	}
+	return fmt.Sprintf("#%d", i)
+}
+
 func (c Config) PrintDebug() {
 	if c.Debug {
 		fmt.Println("Viper debug:")
@@ -230,6 +756,7 @@ func ParseConfig() (*Config, error) {
 	viper.SetDefault("status.color", "red")
 	viper.SetDefault("status.bold", true)
 	viper.SetDefault("status.printInterval", 2*time.Second)
+	viper.SetDefault("waitFor.timeout", DefaultWaitForTimeout)
 
 	var cfg Config
 	if err := viper.Unmarshal(&cfg,
@@ -245,5 +772,9 @@ func ParseConfig() (*Config, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
+	cfg.applyDefaults()
+	if err := cfg.expandEnv(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func parseYAML(t *testing.T, yaml string) (*Config, error) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigType("yaml")
+	if err := readConfig([]byte(yaml)); err != nil {
+		t.Fatal(err)
+	}
+	return ParseConfig()
+}
+
+func TestValidateDependencies(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		err  string
+	}{
+		{
+			name: "valid",
+			yaml: `
+commands:
+  - name: db
+    command: "true"
+    healthCheck:
+      type: tcp
+      address: localhost:5432
+  - name: migrate
+    command: "true"
+    dependsOn:
+      - name: db
+        condition: healthy
+  - name: api
+    command: "true"
+    dependsOn:
+      - name: migrate
+        condition: completed
+      - name: db
+`,
+		},
+		{
+			name: "unknown command",
+			yaml: `
+commands:
+  - name: api
+    command: "true"
+    dependsOn:
+      - name: db
+`,
+			err: `command "api" depends on unknown command "db"`,
+		},
+		{
+			name: "unnamed command",
+			yaml: `
+commands:
+  - command: "./api"
+    dependsOn:
+      - name: db
+`,
+			err: `command "./api" depends on unknown command "db"`,
+		},
+		{
+			name: "ambiguous name",
+			yaml: `
+commands:
+  - name: db
+    command: "true"
+  - name: db
+    command: "true"
+  - name: api
+    command: "true"
+    dependsOn:
+      - name: db
+`,
+			err: `command "api" depends on ambiguous command name "db"`,
+		},
+		{
+			name: "healthy without healthCheck",
+			yaml: `
+commands:
+  - name: db
+    command: "true"
+  - name: api
+    command: "true"
+    dependsOn:
+      - name: db
+        condition: healthy
+`,
+			err: `command "api" depends on "db" being healthy, but it has no healthCheck`,
+		},
+		{
+			name: "invalid healthCheck",
+			yaml: `
+commands:
+  - name: db
+    command: "true"
+    healthCheck:
+      type: tcp
+  - name: api
+    command: "true"
+    dependsOn:
+      - name: db
+        condition: healthy
+`,
+			err: "empty address",
+		},
+		{
+			name: "cycle",
+			yaml: `
+commands:
+  - name: a
+    command: "true"
+    dependsOn:
+      - name: c
+  - name: b
+    command: "true"
+    dependsOn:
+      - name: a
+  - name: c
+    command: "true"
+    dependsOn:
+      - name: b
+`,
+			err: "dependency cycle: a -> c -> b -> a",
+		},
+		{
+			name: "self dependency",
+			yaml: `
+commands:
+  - name: a
+    command: "true"
+    dependsOn:
+      - name: a
+`,
+			err: "dependency cycle: a -> a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseYAML(t, tt.yaml)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDependencyHealthCheckDefaults(t *testing.T) {
+	cfg, err := parseYAML(t, `
+commands:
+  - name: db
+    command: "true"
+    healthCheck:
+      type: tcp
+      address: localhost:5432
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := cfg.Commands[0].HealthCheck
+	if check.Interval != DefaultCheckInterval {
+		t.Errorf("interval = %s, want %s", check.Interval, DefaultCheckInterval)
+	}
+}
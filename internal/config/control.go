@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// ControlConfig sets up the control socket, through which the commands can
+// be inspected and controlled with "concur ctl".
+type ControlConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Socket  string `mapstructure:"socket"`
+}
+
+func (c Config) validateControl() error {
+	if c.Control.Enabled && c.Raw {
+		return errors.New("control is not supported in raw mode")
+	}
+	return nil
+}
+
+// SocketPath returns the configured socket or the default one, which lives
+// in $XDG_RUNTIME_DIR and is named after the config file or, without one,
+// the working directory, so that concur ctl finds it from the same place.
+// Without $XDG_RUNTIME_DIR it lives in a directory of the user in the
+// temporary directory, which is created if needed.
+func (c ControlConfig) SocketPath() (string, error) {
+	if c.Socket != "" {
+		return c.Socket, nil
+	}
+
+	key := viper.ConfigFileUsed()
+	if key == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		key = cwd
+	} else if abs, err := filepath.Abs(key); err == nil {
+		key = abs
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		// The temporary directory is shared, so every user gets their own,
+		// which must not have been created by someone else beforehand.
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("concur-%d", os.Getuid()))
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return "", err
+		}
+		if err := checkPrivateDir(dir); err != nil {
+			return "", fmt.Errorf("control socket: %w", err)
+		}
+	} else {
+		dir = filepath.Join(dir, "concur")
+	}
+	return filepath.Join(dir, fmt.Sprintf("%08x.sock", h.Sum32())), nil
+}
@@ -0,0 +1,104 @@
+//go:build !windows
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckPrivateDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, path string)
+		wantErr string
+	}{
+		{
+			name:  "private",
+			setup: func(t *testing.T, path string) { mkdir(t, path, 0o700) },
+		},
+		{
+			name:    "readable by others",
+			setup:   func(t *testing.T, path string) { mkdir(t, path, 0o755) },
+			wantErr: "mode 0755",
+		},
+		{
+			name: "link",
+			setup: func(t *testing.T, path string) {
+				target := filepath.Join(t.TempDir(), "target")
+				mkdir(t, target, 0o700)
+				if err := os.Symlink(target, path); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "not a directory",
+		},
+		{
+			name: "file",
+			setup: func(t *testing.T, path string) {
+				if err := os.WriteFile(path, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "not a directory",
+		},
+		{
+			name:    "missing",
+			setup:   func(t *testing.T, path string) {},
+			wantErr: "no such file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "concur")
+			tt.setup(t, path)
+
+			err := checkPrivateDir(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSocketPathRefusesForeignDir(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("TMPDIR", tmp)
+
+	path, err := ControlConfig{}.SocketPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Dir(path)
+	if filepath.Dir(dir) != tmp {
+		t.Fatalf("socket %s is not in the temporary directory %s", path, tmp)
+	}
+
+	// Someone else could have created the directory with other permissions.
+	if err := os.Chmod(dir, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (ControlConfig{}).SocketPath(); err == nil {
+		t.Error("the socket is placed in a directory others can write to")
+	}
+}
+
+func mkdir(t *testing.T, path string, perm os.FileMode) {
+	t.Helper()
+	if err := os.Mkdir(path, perm); err != nil {
+		t.Fatal(err)
+	}
+	// The umask could have removed some of the permissions.
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatal(err)
+	}
+}
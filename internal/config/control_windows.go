@@ -0,0 +1,22 @@
+//go:build windows
+
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// checkPrivateDir returns an error unless dir is a directory and not a link
+// to one. The temporary directory is already private to the user on
+// Windows.
+func checkPrivateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
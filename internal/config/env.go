@@ -0,0 +1,299 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// environ is a set of environment variables that keeps track of the order
+// in which they were set.
+type environ struct {
+	keys   []string
+	values map[string]string
+}
+
+func newEnviron(base []string) *environ {
+	e := &environ{values: make(map[string]string, len(base))}
+	for _, kv := range base {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			e.set(key, value)
+		}
+	}
+	return e
+}
+
+func (e *environ) clone() *environ {
+	return &environ{keys: slices.Clone(e.keys), values: maps.Clone(e.values)}
+}
+
+func (e *environ) set(key, value string) {
+	if _, ok := e.values[key]; !ok {
+		e.keys = append(e.keys, key)
+	}
+	e.values[key] = value
+}
+
+// expand interpolates ${VAR} and $VAR with the variables set so far.
+func (e *environ) expand(s string) string {
+	return os.Expand(s, func(key string) string {
+		return e.values[key]
+	})
+}
+
+func (e *environ) list() []string {
+	list := make([]string, len(e.keys))
+	for i, key := range e.keys {
+		list[i] = key + "=" + e.values[key]
+	}
+	return list
+}
+
+// apply adds the variables of the env files and afterwards the ones of the
+// env map, so that the map takes precedence. Values may reference variables
+// of lower layers and other keys of the same map with ${VAR}. A key that
+// references itself, directly or through other keys, sees the value of the
+// lower layers, like PATH: ${PATH}:/bin.
+func (e *environ) apply(envFiles []string, env map[string]string) error {
+	for _, file := range envFiles {
+		if err := e.loadDotenv(file); err != nil {
+			return err
+		}
+	}
+
+	base := e.clone()
+	resolved := make(map[string]string, len(env))
+	resolving := make(map[string]bool)
+	var resolve func(key string) string
+	resolve = func(key string) string {
+		if value, ok := resolved[key]; ok {
+			return value
+		}
+		resolving[key] = true
+		value := os.Expand(env[key], func(ref string) string {
+			if _, ok := env[ref]; ok && !resolving[ref] {
+				return resolve(ref)
+			}
+			return base.values[ref]
+		})
+		delete(resolving, key)
+		resolved[key] = value
+		return value
+	}
+
+	keys := slices.Sorted(maps.Keys(env))
+	for _, key := range keys {
+		e.set(key, resolve(key))
+	}
+	return nil
+}
+
+// loadDotenv reads a file with KEY=VALUE lines. Lines may start with
+// "export ", values may be quoted and "#" starts a comment. Single quoted
+// values are taken literally, all others are interpolated.
+func (e *environ) loadDotenv(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("env file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNr := 1; scanner.Scan(); lineNr++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("env file %s:%d: invalid line", path, lineNr)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+			value = e.expand(value)
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+			value = e.expand(value)
+		}
+		e.set(key, value)
+	}
+	return scanner.Err()
+}
+
+// globalEnv returns the environment of concur with the global envFile and
+// env, each layer taking precedence over the previous ones.
+func (c *Config) globalEnv() (*environ, error) {
+	env := newEnviron(os.Environ())
+	if err := env.apply(c.EnvFile, c.Env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// commandEnv returns the global environment with the envFile and env of the
+// command, which take precedence.
+func (c *Config) commandEnv(command RunCommandConfig) (*environ, error) {
+	env, err := c.globalEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := env.apply(command.EnvFile, command.Env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// Environ returns the environment of the status and waitFor checks: the
+// environment of concur with the global envFile and env.
+func (c *Config) Environ() ([]string, error) {
+	env, err := c.globalEnv()
+	if err != nil {
+		return nil, err
+	}
+	return env.list(), nil
+}
+
+// CommandEnviron returns the environment of a command and its health check,
+// which adds the envFile and env of the command to Environ.
+func (c *Config) CommandEnviron(command RunCommandConfig) ([]string, error) {
+	env, err := c.commandEnv(command)
+	if err != nil {
+		return nil, err
+	}
+	return env.list(), nil
+}
+
+// expandEnv reads the env files, so that missing ones are reported early,
+// and interpolates the headers of the checks with the environment they run
+// in.
+func (c *Config) expandEnv() error {
+	global, err := c.globalEnv()
+	if err != nil {
+		return err
+	}
+	resolve := func(command *RunCommandConfig) error {
+		env, err := c.commandEnv(*command)
+		if err != nil {
+			return err
+		}
+		if command.HealthCheck != nil {
+			command.HealthCheck.expandHeaders(env)
+		}
+		return nil
+	}
+	for i := range c.Commands {
+		if err := resolve(&c.Commands[i]); err != nil {
+			return err
+		}
+	}
+	for i := range c.RunBefore.Commands {
+		if err := resolve(&c.RunBefore.Commands[i].RunCommandConfig); err != nil {
+			return err
+		}
+	}
+	for i := range c.RunAfter.Commands {
+		if err := resolve(&c.RunAfter.Commands[i].RunCommandConfig); err != nil {
+			return err
+		}
+	}
+	for i := range c.Status.Checks {
+		c.Status.Checks[i].expandHeaders(global)
+	}
+	for i := range c.WaitFor.Checks {
+		c.WaitFor.Checks[i].expandHeaders(global)
+	}
+	return nil
+}
+
+// expandHeaders interpolates ${VAR} and $VAR in the header values, so that
+// tokens don't have to be written into the config file.
+func (c *StatusCheckConfig) expandHeaders(env *environ) {
+	for key, value := range c.Headers {
+		c.Headers[key] = env.expand(value)
+	}
+}
+
+// ReadInConfig reads the config file like viper.ReadInConfig. Viper
+// lowercases every key it reads, but the keys of env maps are environment
+// variables, which are case sensitive. So YAML and JSON files are decoded
+// here once more, see readConfig.
+func ReadInConfig() error {
+	if err := viper.ReadInConfig(); err != nil {
+		return err
+	}
+	file := viper.ConfigFileUsed()
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return readConfig(data)
+}
+
+// readConfig replaces the settings read by viper with the ones of the YAML
+// or JSON data, in which the env maps are map[string]string. Viper only
+// lowercases the keys of map[string]any, so their keys keep their case.
+func readConfig(data []byte) error {
+	var settings map[string]any
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+	keepEnvCase(settings)
+	if err := viper.ReadConfig(strings.NewReader("{}")); err != nil {
+		return err
+	}
+	return viper.MergeConfigMap(settings)
+}
+
+// keepEnvCase turns the global env map and the ones of the commands into
+// map[string]string.
+func keepEnvCase(settings map[string]any) {
+	for key, value := range settings {
+		switch strings.ToLower(key) {
+		case "env":
+			if env, ok := value.(map[string]any); ok {
+				values := make(map[string]string, len(env))
+				for k, v := range env {
+					if v != nil {
+						values[k] = fmt.Sprint(v)
+					} else {
+						values[k] = ""
+					}
+				}
+				settings[key] = values
+			}
+		case "commands":
+			commands, _ := value.([]any)
+			for _, command := range commands {
+				if command, ok := command.(map[string]any); ok {
+					keepEnvCase(command)
+				}
+			}
+		case "runbefore", "runafter":
+			if section, ok := value.(map[string]any); ok {
+				keepEnvCase(section)
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// envMap turns an environment list into a map.
+func envMap(list []string) map[string]string {
+	m := make(map[string]string, len(list))
+	for _, kv := range list {
+		key, value, _ := strings.Cut(kv, "=")
+		m[key] = value
+	}
+	return m
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDotenv(t *testing.T) {
+	path := writeFile(t, ".env", `
+# comment
+PLAIN=value
+export EXPORTED=yes
+SPACED = trimmed  
+COMMENTED=value # comment
+SINGLE='${PLAIN} # literal'
+DOUBLE="line\nnext \"quoted\" ${PLAIN}"
+REF=${PLAIN}-$EXPORTED
+OUTER=${CONCUR_TEST_OUTER}
+EMPTY=
+`)
+	t.Setenv("CONCUR_TEST_OUTER", "outer")
+
+	e := newEnviron(os.Environ())
+	if err := e.loadDotenv(path); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"PLAIN":     "value",
+		"EXPORTED":  "yes",
+		"SPACED":    "trimmed",
+		"COMMENTED": "value",
+		"SINGLE":    "${PLAIN} # literal",
+		"DOUBLE":    "line\nnext \"quoted\" value",
+		"REF":       "value-yes",
+		"OUTER":     "outer",
+		"EMPTY":     "",
+	}
+	for key, value := range want {
+		if got, ok := e.values[key]; !ok || got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestLoadDotenvErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{name: "no assignment", content: "A=1\nINVALID\n", err: ":2: invalid line"},
+		{name: "empty key", content: "=value\n", err: ":1: invalid line"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, ".env", tt.content)
+			err := newEnviron(nil).loadDotenv(path)
+			if err == nil || !strings.HasSuffix(err.Error(), tt.err) {
+				t.Fatalf("err = %v, want suffix %q", err, tt.err)
+			}
+		})
+	}
+
+	err := newEnviron(nil).loadDotenv(filepath.Join(t.TempDir(), "missing"))
+	if err == nil {
+		t.Error("missing env file is no error")
+	}
+}
+
+func TestEnvPrecedence(t *testing.T) {
+	t.Setenv("CONCUR_TEST_LAYER", "concur")
+	t.Setenv("CONCUR_TEST_OUTER", "outer")
+	globalFile := writeFile(t, "global.env", "LAYER=global file\nGLOBAL_FILE=${CONCUR_TEST_OUTER}\nSHADOWED=global file\n")
+	commandFile := writeFile(t, "command.env", "LAYER=command file\nCOMMAND_FILE=${GLOBAL}\n")
+
+	cfg, err := parseYAML(t, `
+envFile: [`+globalFile+`]
+env:
+  LAYER: global
+  GLOBAL: ${LAYER}
+  CONCUR_TEST_LAYER: ${CONCUR_TEST_LAYER} and global
+commands:
+  - command: "true"
+    envFile: [`+commandFile+`]
+    env:
+      LAYER: command
+      COMMAND: ${LAYER}
+      SHADOWED: command
+  - command: "true"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	global, err := cfg.Environ()
+	if err != nil {
+		t.Fatal(err)
+	}
+	command, err := cfg.CommandEnviron(cfg.Commands[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := cfg.CommandEnviron(cfg.Commands[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		env  []string
+		want map[string]string
+	}{
+		{
+			name: "global",
+			env:  global,
+			want: map[string]string{
+				"LAYER":             "global",
+				"GLOBAL":            "global",
+				"GLOBAL_FILE":       "outer",
+				"SHADOWED":          "global file",
+				"CONCUR_TEST_LAYER": "concur and global",
+			},
+		},
+		{
+			name: "command",
+			env:  command,
+			want: map[string]string{
+				"LAYER":        "command",
+				"COMMAND":      "command",
+				"COMMAND_FILE": "global",
+				"GLOBAL_FILE":  "outer",
+				"SHADOWED":     "command",
+			},
+		},
+		{
+			name: "command without env",
+			env:  plain,
+			want: map[string]string{"LAYER": "global", "SHADOWED": "global file"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := envMap(tt.env)
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("%s = %q, want %q", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvReferencesInSameMap(t *testing.T) {
+	t.Setenv("CONCUR_TEST_PATH", "/usr/bin")
+	e := newEnviron(os.Environ())
+	err := e.apply(nil, map[string]string{
+		"URL":              "${BASE}/y",
+		"BASE":             "/x",
+		"DEEP":             "${URL}/z",
+		"CONCUR_TEST_PATH": "${CONCUR_TEST_PATH}:/opt/bin",
+		// A cycle falls back to the lower layers, where A and B are unset.
+		"A": "a${B}",
+		"B": "b${A}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"URL":              "/x/y",
+		"BASE":             "/x",
+		"DEEP":             "/x/y/z",
+		"CONCUR_TEST_PATH": "/usr/bin:/opt/bin",
+		"A":                "ab",
+		"B":                "b",
+	}
+	for key, value := range want {
+		if got := e.values[key]; got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestEnvKeepsCase(t *testing.T) {
+	cfg, err := parseYAML(t, `
+env:
+  MixedCase: global
+  lower: lower
+commands:
+  - command: "true"
+    env:
+      Api_Key: secret
+runBefore:
+  commands:
+    - command: "true"
+      env:
+        NODE_ENV: test
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Env["MixedCase"] != "global" || cfg.Env["lower"] != "lower" {
+		t.Errorf("global env = %v", cfg.Env)
+	}
+	if cfg.Commands[0].Env["Api_Key"] != "secret" {
+		t.Errorf("command env = %v", cfg.Commands[0].Env)
+	}
+	if cfg.RunBefore.Commands[0].Env["NODE_ENV"] != "test" {
+		t.Errorf("runBefore env = %v", cfg.RunBefore.Commands[0].Env)
+	}
+}
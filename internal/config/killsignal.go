@@ -8,6 +8,14 @@ import (
 
 type KillSignal syscall.Signal
 
+var killSignalMap = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGKILL": syscall.SIGKILL,
+}
+
 func (c KillSignal) Validate() error {
 	return nil
 }
@@ -18,18 +26,24 @@ func (c KillSignal) Sys() syscall.Signal {
 
 // Satisfy the flag package  Value interface.
 func (s *KillSignal) Set(str string) error {
-	if strings.ToUpper(str) == "SIGINT" {
-		*s = KillSignal(syscall.SIGINT)
-	} else if strings.ToUpper(str) == "SIGTERM" {
-		*s = KillSignal(syscall.SIGTERM)
-	} else if strings.ToUpper(str) == "SIGKILL" {
-		*s = KillSignal(syscall.SIGKILL)
-	} else {
+	sig, ok := killSignalMap[strings.ToUpper(str)]
+	if !ok {
 		return fmt.Errorf("invalid kill signal: %s", str)
 	}
+	*s = KillSignal(sig)
 	return nil
 }
 
+// Satisfy the fmt.Stringer interface.
+func (s KillSignal) String() string {
+	for name, sig := range killSignalMap {
+		if sig == s.Sys() {
+			return name
+		}
+	}
+	return s.Sys().String()
+}
+
 // Satisfy the pflag package Value interface.
 func (s *KillSignal) Type() string { return "kill-signal" }
 
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogConfig sets how the output of the commands is written to their log files.
+type LogConfig struct {
+	Prefix     bool          `mapstructure:"prefix"`
+	StripANSI  bool          `mapstructure:"stripAnsi"`
+	MaxSize    ByteSize      `mapstructure:"maxSize"`
+	MaxAge     time.Duration `mapstructure:"maxAge"`
+	MaxBackups int           `mapstructure:"maxBackups"`
+	Compress   bool          `mapstructure:"compress"`
+}
+
+func (c LogConfig) Validate() error {
+	if err := c.MaxSize.Validate(); err != nil {
+		return err
+	}
+	if c.MaxAge < 0 {
+		return errors.New("invalid log maxAge")
+	}
+	if c.MaxBackups < 0 {
+		return errors.New("invalid log maxBackups")
+	}
+	return nil
+}
+
+func (c Config) validateLogs() error {
+	if !c.Raw {
+		return nil
+	}
+	if c.LogDir != "" {
+		return errors.New("logDir is not supported in raw mode")
+	}
+	for _, command := range c.Commands {
+		if command.LogFile != "" {
+			return errors.New("logFile is not supported in raw mode")
+		}
+	}
+	return nil
+}
+
+// LogFile returns the path of the log file of the command at the given index.
+// The logFile of the command takes precedence over the global logDir, in which
+// the file is named after the command. It returns "" if there is no log file.
+func (c Config) LogFile(idx int) string {
+	command := c.Commands[idx]
+	if command.LogFile != "" {
+		return command.LogFile
+	}
+	if c.LogDir == "" {
+		return ""
+	}
+	name := command.Name
+	if name == "" {
+		name = strconv.Itoa(idx)
+	}
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+	return filepath.Join(c.LogDir, name+".log")
+}
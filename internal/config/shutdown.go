@@ -0,0 +1,70 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout is the time a command gets to exit after the kill
+// signal before it is killed, if no shutdown sequence is configured.
+const DefaultShutdownTimeout = 5 * time.Second
+
+type ShutdownStep struct {
+	Signal  KillSignal    `mapstructure:"signal"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+func (c ShutdownStep) Validate() error {
+	if c.Signal == 0 {
+		return errors.New("empty shutdown signal")
+	} else if c.Timeout < 0 {
+		return errors.New("shutdown timeout must not be negative")
+	}
+	return c.Signal.Validate()
+}
+
+// ShutdownConfig is the sequence of signals sent to a command when it has to
+// stop. After each signal the command gets the timeout of the step to exit
+// before the next signal is sent. A sequence that does not end with SIGKILL
+// is finished with it, see WithKill.
+type ShutdownConfig []ShutdownStep
+
+func (c ShutdownConfig) Validate() error {
+	for _, step := range c {
+		if err := step.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithKill returns the sequence ending with SIGKILL, so that a command which
+// ignores every other signal still exits. If the last step is no SIGKILL, it
+// is sent after the timeout of that step, or DefaultShutdownTimeout if it
+// has none. An empty sequence only kills.
+func (c ShutdownConfig) WithKill() ShutdownConfig {
+	if len(c) == 0 {
+		return ShutdownConfig{{Signal: KillSignal(syscall.SIGKILL)}}
+	} else if c[len(c)-1].Signal.Sys() == syscall.SIGKILL {
+		return c
+	}
+	steps := slices.Clone(c)
+	if steps[len(steps)-1].Timeout == 0 {
+		steps[len(steps)-1].Timeout = DefaultShutdownTimeout
+	}
+	return append(steps, ShutdownStep{Signal: KillSignal(syscall.SIGKILL)})
+}
+
+// defaultShutdown sends the kill signal and kills the command if it did not
+// exit within DefaultShutdownTimeout.
+func defaultShutdown(killSignal KillSignal) ShutdownConfig {
+	if killSignal.Sys() == syscall.SIGKILL {
+		return ShutdownConfig{{Signal: killSignal}}
+	}
+	return ShutdownConfig{
+		{Signal: killSignal, Timeout: DefaultShutdownTimeout},
+		{Signal: KillSignal(syscall.SIGKILL)},
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShutdownWithKill(t *testing.T) {
+	sigint := KillSignal(syscall.SIGINT)
+	sigkill := KillSignal(syscall.SIGKILL)
+	tests := []struct {
+		name  string
+		steps ShutdownConfig
+		want  ShutdownConfig
+	}{
+		{
+			name: "empty",
+			want: ShutdownConfig{{Signal: sigkill}},
+		},
+		{
+			name:  "ends with SIGKILL",
+			steps: ShutdownConfig{{Signal: sigint, Timeout: time.Second}, {Signal: sigkill}},
+			want:  ShutdownConfig{{Signal: sigint, Timeout: time.Second}, {Signal: sigkill}},
+		},
+		{
+			name:  "SIGKILL appended",
+			steps: ShutdownConfig{{Signal: sigint, Timeout: 10 * time.Second}},
+			want:  ShutdownConfig{{Signal: sigint, Timeout: 10 * time.Second}, {Signal: sigkill}},
+		},
+		{
+			name:  "default timeout",
+			steps: ShutdownConfig{{Signal: sigint}},
+			want:  ShutdownConfig{{Signal: sigint, Timeout: DefaultShutdownTimeout}, {Signal: sigkill}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.steps.WithKill()
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("WithKill() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a size in bytes, that can be written like 512KB or 10MB.
+type ByteSize int64
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   ByteSize
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+func (s ByteSize) Validate() error {
+	if s < 0 {
+		return fmt.Errorf("invalid size: %d", s)
+	}
+	return nil
+}
+
+// Satisfy the flag package  Value interface.
+func (s *ByteSize) Set(str string) error {
+	text := strings.ToUpper(strings.TrimSpace(str))
+	unit := ByteSize(1)
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(text, u.suffix) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, u.suffix))
+			unit = u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size: %s", str)
+	}
+	*s = ByteSize(n) * unit
+	return nil
+}
+
+// Satisfy the fmt.Stringer interface.
+func (s ByteSize) String() string {
+	for _, u := range byteSizeUnits {
+		if s >= u.size && s%u.size == 0 {
+			return fmt.Sprintf("%d%s", s/u.size, u.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", int64(s))
+}
+
+// Satisfy the pflag package Value interface.
+func (s *ByteSize) Type() string { return "size" }
+
+// Satisfy the encoding.TextUnmarshaler interface.
+func (s *ByteSize) UnmarshalText(text []byte) error {
+	return s.Set(string(text))
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SuccessCondition decides which commands have to succeed for concur to
+// exit successfully, like the --success option of concurrently.
+type SuccessCondition string
+
+const (
+	SuccessConditionAll   SuccessCondition = "all"
+	SuccessConditionFirst SuccessCondition = "first"
+	SuccessConditionLast  SuccessCondition = "last"
+
+	successCommandPrefix = "command-"
+)
+
+func (s SuccessCondition) Validate() error {
+	switch s {
+	case SuccessConditionAll, SuccessConditionFirst, SuccessConditionLast, "":
+		return nil
+	}
+	if target, _ := s.Command(); target != "" {
+		return nil
+	}
+	return fmt.Errorf("invalid success condition: %s", s)
+}
+
+// Command returns the name or index of the command for the command-<name>
+// and !command-<name> conditions and whether the condition is negated.
+func (s SuccessCondition) Command() (target string, negated bool) {
+	str := string(s)
+	if strings.HasPrefix(str, "!") {
+		str, negated = str[1:], true
+	}
+	if !strings.HasPrefix(str, successCommandPrefix) {
+		return "", false
+	}
+	return str[len(successCommandPrefix):], negated
+}
+
+// Matches reports whether the command-<name> target refers to the command
+// with the given index and name.
+func (s SuccessCondition) Matches(index int, name string) bool {
+	target, _ := s.Command()
+	return target != "" && MatchesCommand(target, index, name)
+}
+
+// MatchesCommand reports whether the target, either an index or a name,
+// refers to the command with the given index and name.
+func MatchesCommand(target string, index int, name string) bool {
+	if i, err := strconv.Atoi(target); err == nil {
+		return i == index
+	}
+	return target == name
+}
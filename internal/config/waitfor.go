@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const DefaultWaitForTimeout = time.Minute
+
+// WaitForConfig holds back the commands after runBefore until the checks are
+// healthy, e.g. until a database started with docker compose accepts
+// connections.
+type WaitForConfig struct {
+	Timeout time.Duration `mapstructure:"timeout"`
+	// StatusChecks are indexes of status.checks.
+	StatusChecks []int               `mapstructure:"statusChecks"`
+	Checks       []StatusCheckConfig `mapstructure:"checks"`
+}
+
+// Enabled reports whether there is anything to wait for.
+func (c WaitForConfig) Enabled() bool {
+	return len(c.StatusChecks) > 0 || len(c.Checks) > 0
+}
+
+// AllChecks returns the referenced status checks followed by the own checks.
+func (c WaitForConfig) AllChecks(status StatusConfig) []StatusCheckConfig {
+	checks := make([]StatusCheckConfig, 0, len(c.StatusChecks)+len(c.Checks))
+	for _, idx := range c.StatusChecks {
+		checks = append(checks, status.Checks[idx])
+	}
+	return append(checks, c.Checks...)
+}
+
+func (c Config) validateWaitFor() error {
+	if c.WaitFor.Timeout < 0 {
+		return errors.New("waitFor: invalid timeout")
+	}
+	for _, idx := range c.WaitFor.StatusChecks {
+		if idx < 0 || idx >= len(c.Status.Checks) {
+			return fmt.Errorf("waitFor: status check %d does not exist", idx)
+		}
+		// The status checks are only validated if the status is enabled.
+		if err := c.Status.Checks[idx].Validate(); err != nil {
+			return fmt.Errorf("waitFor: status check %d: %w", idx, err)
+		}
+	}
+	for _, check := range c.WaitFor.Checks {
+		if err := check.Validate(); err != nil {
+			return fmt.Errorf("waitFor: %w", err)
+		}
+	}
+	return nil
+}
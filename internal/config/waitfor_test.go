@@ -0,0 +1,111 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitForDisabledStatusCheckDefaults(t *testing.T) {
+	cfg, err := parseYAML(t, `
+commands:
+  - command: "true"
+status:
+  enabled: false
+  checks:
+    - type: tcp
+      address: localhost:5432
+waitFor:
+  statusChecks: [0]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := cfg.WaitFor.AllChecks(cfg.Status)
+	if len(checks) != 1 {
+		t.Fatalf("got %d checks, want 1", len(checks))
+	}
+	if checks[0].Interval != DefaultCheckInterval {
+		t.Errorf("interval = %s, want %s", checks[0].Interval, DefaultCheckInterval)
+	}
+	if checks[0].Timeout != DefaultCheckInterval/2 {
+		t.Errorf("timeout = %s, want %s", checks[0].Timeout, DefaultCheckInterval/2)
+	}
+	if cfg.WaitFor.Timeout != DefaultWaitForTimeout {
+		t.Errorf("waitFor timeout = %s, want %s", cfg.WaitFor.Timeout, DefaultWaitForTimeout)
+	}
+}
+
+func TestWaitForValidatesDisabledStatusChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		err  string
+	}{
+		{
+			name: "missing address",
+			yaml: `
+commands:
+  - command: "true"
+status:
+  checks:
+    - type: tcp
+waitFor:
+  statusChecks: [0]
+`,
+			err: "waitFor: status check 0: empty address",
+		},
+		{
+			name: "interval too small",
+			yaml: `
+commands:
+  - command: "true"
+status:
+  checks:
+    - type: tcp
+      address: localhost:5432
+      interval: 10ms
+waitFor:
+  statusChecks: [0]
+`,
+			err: "waitFor: status check 0: interval too small",
+		},
+		{
+			name: "unknown index",
+			yaml: `
+commands:
+  - command: "true"
+waitFor:
+  statusChecks: [1]
+`,
+			err: "waitFor: status check 1 does not exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseYAML(t, tt.yaml)
+			if err == nil || err.Error() != tt.err {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestWaitForCheckIntervalKept(t *testing.T) {
+	cfg, err := parseYAML(t, `
+commands:
+  - command: "true"
+waitFor:
+  checks:
+    - type: grpc
+      address: localhost:50051
+      interval: 500ms
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := cfg.WaitFor.Checks[0]
+	if check.Interval != 500*time.Millisecond || check.Timeout != 250*time.Millisecond {
+		t.Errorf("interval, timeout = %s, %s, want 500ms, 250ms", check.Interval, check.Timeout)
+	}
+}
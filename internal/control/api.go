@@ -0,0 +1,59 @@
+package control
+
+import (
+	"time"
+
+	"github.com/akatranlp/concur/internal/cmd"
+	hc "github.com/akatranlp/concur/internal/health_check"
+	"github.com/akatranlp/concur/internal/logger"
+)
+
+// CommandStatus is the state of a command as returned by the API.
+type CommandStatus struct {
+	Index     int        `json:"index"`
+	Name      string     `json:"name,omitempty"`
+	Command   string     `json:"command"`
+	State     cmd.State  `json:"state"`
+	Pid       int        `json:"pid,omitempty"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	ExitCode  *int       `json:"exitCode,omitempty"`
+	Healthy   *bool      `json:"healthy,omitempty"`
+	Health    hc.Status  `json:"health,omitempty"`
+}
+
+// DisplayName returns the name of the command or the command itself.
+func (s CommandStatus) DisplayName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Command
+}
+
+// CheckStatus is the result of a health check. Status checks have Check set,
+// the health checks of commands have Index set.
+type CheckStatus struct {
+	Check    *int      `json:"check,omitempty"`
+	Index    *int      `json:"index,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Healthy  bool      `json:"healthy"`
+	Status   hc.Status `json:"status"`
+	Messages []string  `json:"messages"`
+}
+
+// LogLine is a line of output of a command. Messages of concur itself have
+// no stream.
+type LogLine struct {
+	Time   time.Time     `json:"time"`
+	Index  int           `json:"index"`
+	Stream logger.Stream `json:"stream,omitempty"`
+	Text   string        `json:"text"`
+}
+
+// SignalRequest is the body of a signal request.
+type SignalRequest struct {
+	Signal string `json:"signal"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,160 @@
+package control
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Client talks to the API of a running concur through its control socket.
+type Client struct {
+	path string
+	http *http.Client
+}
+
+func NewClient(path string) *Client {
+	return &Client{
+		path: path,
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+	}
+}
+
+// Commands returns the state of every command.
+func (c *Client) Commands(ctx context.Context) ([]CommandStatus, error) {
+	var statuses []CommandStatus
+	err := c.do(ctx, http.MethodGet, "/commands", nil, &statuses)
+	return statuses, err
+}
+
+// Restart restarts the command with the given name or index.
+func (c *Client) Restart(ctx context.Context, target string) (CommandStatus, error) {
+	return c.action(ctx, target, "restart", nil)
+}
+
+// Stop stops the command with the given name or index.
+func (c *Client) Stop(ctx context.Context, target string) (CommandStatus, error) {
+	return c.action(ctx, target, "stop", nil)
+}
+
+// Start starts the stopped command with the given name or index.
+func (c *Client) Start(ctx context.Context, target string) (CommandStatus, error) {
+	return c.action(ctx, target, "start", nil)
+}
+
+// Signal sends the signal, like SIGHUP, to the command with the given name or index.
+func (c *Client) Signal(ctx context.Context, target, signal string) (CommandStatus, error) {
+	return c.action(ctx, target, "signal", SignalRequest{Signal: signal})
+}
+
+// Health returns the results of the health checks.
+func (c *Client) Health(ctx context.Context) ([]CheckStatus, error) {
+	var checks []CheckStatus
+	err := c.do(ctx, http.MethodGet, "/health", nil, &checks)
+	return checks, err
+}
+
+// Shutdown shuts the commands down, like Ctrl+C does. concur runs the
+// runAfter commands afterwards.
+func (c *Client) Shutdown(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/shutdown", nil, &struct{}{})
+}
+
+// Logs calls fn with the last lines of the command with the given name or
+// index, or of all commands if target is empty. With follow it keeps calling
+// fn with new lines until the context is done or concur shuts down.
+func (c *Client) Logs(ctx context.Context, target string, lines int, follow bool, fn func(LogLine)) error {
+	path := "/logs"
+	if target != "" {
+		path = "/commands/" + url.PathEscape(target) + "/logs"
+	}
+	query := url.Values{}
+	query.Set("lines", strconv.Itoa(lines))
+	query.Set("follow", strconv.FormatBool(follow))
+
+	res, err := c.request(ctx, http.MethodGet, path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var line LogLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return err
+		}
+		fn(line)
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) action(ctx context.Context, target, action string, body any) (CommandStatus, error) {
+	var status CommandStatus
+	err := c.do(ctx, http.MethodPost, "/commands/"+url.PathEscape(target)+"/"+action, body, &status)
+	return status, err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, result any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	res, err := c.request(ctx, method, path, r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+// request sends the request and turns error responses into errors.
+func (c *Client) request(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, "http://concur"+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, fmt.Errorf("concur is not running with a control socket at %s", c.path)
+		}
+		return nil, err
+	}
+	if res.StatusCode >= 400 {
+		defer res.Body.Close()
+		var e errorResponse
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil || e.Error == "" {
+			return nil, fmt.Errorf("request failed: %s", res.Status)
+		}
+		return nil, errors.New(e.Error)
+	}
+	return res, nil
+}
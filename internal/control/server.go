@@ -0,0 +1,394 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akatranlp/concur/internal/cmd"
+	"github.com/akatranlp/concur/internal/config"
+	hc "github.com/akatranlp/concur/internal/health_check"
+	"github.com/akatranlp/concur/internal/logger"
+	"github.com/akatranlp/concur/internal/prefix"
+)
+
+// maxLogLines is the number of lines kept per command for the logs endpoint.
+const maxLogLines = 1000
+
+// Server serves the HTTP API on the control socket. It sees the messages of
+// the commands on their way to the output, to keep their recent lines and
+// exit codes.
+type Server struct {
+	path     string
+	prefix   *prefix.Prefix
+	commands []*cmd.Command
+	checks   []hc.HealthChecker
+	cancel   context.CancelFunc
+
+	mu        sync.Mutex
+	logs      [][]LogLine
+	exitCodes []*int
+	// followers are the channels of the followed logs with the index of
+	// their command, or -1 for all commands.
+	followers map[chan LogLine]int
+
+	server     *http.Server
+	in         chan logger.Message
+	forwarding bool
+	done       chan struct{}
+}
+
+// NewServer creates the server for the given commands. cancel is called to
+// shut the commands down on a shutdown request.
+func NewServer(path string, p *prefix.Prefix, commands []*cmd.Command, checks []hc.HealthChecker, cancel context.CancelFunc) *Server {
+	return &Server{
+		path:      path,
+		prefix:    p,
+		commands:  commands,
+		checks:    checks,
+		cancel:    cancel,
+		logs:      make([][]LogLine, len(commands)),
+		exitCodes: make([]*int, len(commands)),
+		followers: make(map[chan LogLine]int),
+		in:        make(chan logger.Message, 100),
+		done:      make(chan struct{}),
+	}
+}
+
+// Listen creates the socket and serves the API in the background. A stale
+// socket is replaced, one that another concur still listens on is not.
+func (s *Server) Listen() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	if conn, err := net.Dial("unix", s.path); err == nil {
+		conn.Close()
+		return fmt.Errorf("control socket %s is already in use", s.path)
+	}
+	_ = os.Remove(s.path)
+
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return err
+	}
+	// The API can stop the commands, so only the user may connect.
+	if err := os.Chmod(s.path, 0o600); err != nil {
+		listener.Close()
+		return err
+	}
+	s.server = &http.Server{Handler: s.routes()}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintln(os.Stderr, "control:", err)
+		}
+	}()
+	return nil
+}
+
+// Forward passes the messages on to out after recording them. The returned
+// channel takes the place of out and is closed by Close.
+func (s *Server) Forward(out chan<- logger.Message) chan<- logger.Message {
+	s.forwarding = true
+	go func() {
+		defer close(s.done)
+		for msg := range s.in {
+			s.record(msg)
+			out <- msg
+		}
+	}()
+	return s.in
+}
+
+// Close forwards the remaining messages, shuts the API down and removes the
+// socket.
+func (s *Server) Close() {
+	if s.forwarding {
+		close(s.in)
+		<-s.done
+	}
+
+	s.mu.Lock()
+	for ch := range s.followers {
+		close(ch)
+	}
+	s.followers = nil
+	s.mu.Unlock()
+
+	if s.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = s.server.Shutdown(ctx)
+		_ = os.Remove(s.path)
+	}
+}
+
+func (s *Server) record(msg logger.Message) {
+	if msg.ID < 0 || msg.ID >= len(s.commands) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if event := msg.Event; event != nil && event.Type == logger.EventExit {
+		code := event.Code
+		s.exitCodes[msg.ID] = &code
+	}
+	if msg.Text == "" {
+		return
+	}
+
+	line := LogLine{
+		Time:   time.Now(),
+		Index:  msg.ID,
+		Stream: msg.Stream,
+		Text:   strings.TrimSuffix(msg.Text, "\n"),
+	}
+	logs := s.logs[msg.ID]
+	if len(logs) >= maxLogLines {
+		logs = logs[len(logs)-maxLogLines+1:]
+	}
+	s.logs[msg.ID] = append(logs, line)
+
+	for ch, idx := range s.followers {
+		if idx != -1 && idx != msg.ID {
+			continue
+		}
+		// Slow readers miss lines instead of blocking the output.
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /commands", s.handleCommands)
+	mux.HandleFunc("GET /commands/{target}", s.withCommand(s.handleCommand))
+	mux.HandleFunc("POST /commands/{target}/restart", s.withCommand(s.handleAction((*cmd.Command).Restart)))
+	mux.HandleFunc("POST /commands/{target}/stop", s.withCommand(s.handleAction((*cmd.Command).Stop)))
+	mux.HandleFunc("POST /commands/{target}/start", s.withCommand(s.handleAction((*cmd.Command).Start)))
+	mux.HandleFunc("POST /commands/{target}/signal", s.withCommand(s.handleSignal))
+	mux.HandleFunc("GET /commands/{target}/logs", s.withCommand(s.handleLogs))
+	mux.HandleFunc("GET /logs", func(w http.ResponseWriter, r *http.Request) {
+		s.handleLogs(w, r, -1)
+	})
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("POST /shutdown", func(w http.ResponseWriter, _ *http.Request) {
+		s.cancel()
+		writeJSON(w, http.StatusOK, struct{}{})
+	})
+	return mux
+}
+
+// withCommand looks up the command by the name or index in the path.
+func (s *Server) withCommand(handler func(http.ResponseWriter, *http.Request, int)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target := r.PathValue("target")
+		idx, ok := s.prefix.Lookup(target)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("unknown command: %s", target))
+			return
+		}
+		handler(w, r, idx)
+	}
+}
+
+func (s *Server) handleCommands(w http.ResponseWriter, _ *http.Request) {
+	statuses := make([]CommandStatus, len(s.commands))
+	for i := range s.commands {
+		statuses[i] = s.status(i)
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *Server) handleCommand(w http.ResponseWriter, _ *http.Request, idx int) {
+	writeJSON(w, http.StatusOK, s.status(idx))
+}
+
+func (s *Server) handleAction(action func(*cmd.Command) error) func(http.ResponseWriter, *http.Request, int) {
+	return func(w http.ResponseWriter, _ *http.Request, idx int) {
+		if err := action(s.commands[idx]); err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.status(idx))
+	}
+}
+
+func (s *Server) handleSignal(w http.ResponseWriter, r *http.Request, idx int) {
+	var req SignalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var sig config.KillSignal
+	if err := sig.Set(req.Signal); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.commands[idx].Signal(sig.Sys()); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.status(idx))
+}
+
+// handleLogs writes the last lines of the command, or of all commands with
+// idx -1, as JSON Lines. With follow it keeps writing new lines until the
+// client or the server goes away.
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request, idx int) {
+	lines := 100
+	if value := r.URL.Query().Get("lines"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid lines: %s", value))
+			return
+		}
+		lines = n
+	}
+	follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+
+	var ch chan LogLine
+	s.mu.Lock()
+	recent := s.recentLogs(idx, lines)
+	if follow && s.followers != nil {
+		ch = make(chan LogLine, 100)
+		s.followers[ch] = idx
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/jsonl")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, line := range recent {
+		_ = enc.Encode(line)
+	}
+	if ch == nil {
+		return
+	}
+	defer s.unfollow(ch)
+
+	flusher, _ := w.(http.Flusher)
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case line, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := enc.Encode(line); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// recentLogs returns the last n lines in the order they were written. Has
+// to be called with s.mu held.
+func (s *Server) recentLogs(idx, n int) []LogLine {
+	var lines []LogLine
+	if idx >= 0 {
+		lines = s.logs[idx]
+	} else {
+		for _, logs := range s.logs {
+			lines = append(lines, logs...)
+		}
+		slices.SortStableFunc(lines, func(a, b LogLine) int { return a.Time.Compare(b.Time) })
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return append([]LogLine(nil), lines...)
+}
+
+func (s *Server) unfollow(ch chan LogLine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.followers[ch]; ok {
+		delete(s.followers, ch)
+		close(ch)
+	}
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	checks := make([]CheckStatus, 0)
+	for i, checker := range s.checks {
+		checks = append(checks, CheckStatus{
+			Check:    &i,
+			Healthy:  checker.Healthy(),
+			Status:   checker.Status(),
+			Messages: stripMessages(checker.Messages()),
+		})
+	}
+	for i, c := range s.commands {
+		checker := c.HealthChecker()
+		if checker == nil {
+			continue
+		}
+		checks = append(checks, CheckStatus{
+			Index:    &i,
+			Name:     s.prefix.Data(i).Name,
+			Healthy:  checker.Healthy(),
+			Status:   checker.Status(),
+			Messages: stripMessages(checker.Messages()),
+		})
+	}
+	writeJSON(w, http.StatusOK, checks)
+}
+
+func (s *Server) status(idx int) CommandStatus {
+	c := s.commands[idx]
+	data := s.prefix.Data(idx)
+	status := CommandStatus{
+		Index:   idx,
+		Name:    data.Name,
+		Command: data.Command,
+		State:   c.State(),
+		Pid:     c.Pid(),
+	}
+	if startedAt := c.StartedAt(); !startedAt.IsZero() {
+		status.StartedAt = &startedAt
+	}
+	s.mu.Lock()
+	status.ExitCode = s.exitCodes[idx]
+	s.mu.Unlock()
+	if checker := c.HealthChecker(); checker != nil {
+		healthy := checker.Healthy()
+		status.Healthy = &healthy
+		status.Health = checker.Status()
+	}
+	return status
+}
+
+func stripMessages(messages []string) []string {
+	for i, message := range messages {
+		messages[i] = logger.StripANSI(message)
+	}
+	return messages
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,67 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+const followInterval = 200 * time.Millisecond
+
+// Follow writes what is appended to the output file from its current offset
+// on to w, until the process with the given pid exited or the context is done.
+func Follow(ctx context.Context, f *os.File, pid int, w io.Writer) error {
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+	for {
+		// Checked before copying, so that the last output is not missed.
+		alive := Alive(pid)
+		if _, err := io.Copy(w, f); err != nil {
+			return err
+		}
+		if !alive {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// SeekLastLines moves the offset of the file to the start of its last n lines.
+func SeekLastLines(f *os.File, n int) error {
+	const chunk = 32 * 1024
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	offset := end
+	// A trailing newline ends the last line and does not start another one.
+	newlines := -1
+	buf := make([]byte, chunk)
+	for offset > 0 {
+		size := min(int64(chunk), offset)
+		offset -= size
+		if _, err := f.ReadAt(buf[:size], offset); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		data := buf[:size]
+		for i := len(data) - 1; i >= 0; i-- {
+			if data[i] != '\n' {
+				continue
+			}
+			newlines++
+			if newlines == n {
+				_, err := f.Seek(offset+int64(i)+1, io.SeekStart)
+				return err
+			}
+		}
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
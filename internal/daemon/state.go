@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotRunning is returned if there is no concur running in the background.
+var ErrNotRunning = errors.New("concur is not running in the background")
+
+// State describes a concur running in the background. It is written next to
+// the control socket, so that it is found the same way.
+type State struct {
+	Pid       int            `json:"pid"`
+	StartedAt time.Time      `json:"startedAt"`
+	Config    string         `json:"config,omitempty"`
+	Socket    string         `json:"socket"`
+	Output    string         `json:"output"`
+	Commands  []CommandState `json:"commands,omitempty"`
+}
+
+// CommandState is the last known pid and the log file of a command.
+type CommandState struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name,omitempty"`
+	Command string `json:"command"`
+	Pid     int    `json:"pid,omitempty"`
+	LogFile string `json:"logFile,omitempty"`
+}
+
+// Paths returns the paths of the state file and of the output file that
+// belong to the control socket.
+func Paths(socket string) (state, output string) {
+	base := strings.TrimSuffix(socket, filepath.Ext(socket))
+	return base + ".json", base + ".log"
+}
+
+// ReadState reads the state file and returns ErrNotRunning if it does not
+// exist or its process is gone. A stale state file is removed.
+func ReadState(path string) (State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return State{}, ErrNotRunning
+	} else if err != nil {
+		return State{}, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{}, err
+	}
+	if !Alive(state.Pid) {
+		_ = os.Remove(path)
+		return State{}, ErrNotRunning
+	}
+	return state, nil
+}
+
+// StateFile keeps the state file of the running concur up to date.
+type StateFile struct {
+	path string
+
+	mu    sync.Mutex
+	state State
+}
+
+func NewStateFile(path string, state State) *StateFile {
+	return &StateFile{path: path, state: state}
+}
+
+// SetCommands sets the commands and writes the state file.
+func (f *StateFile) SetCommands(commands []CommandState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state.Commands = commands
+	return f.write()
+}
+
+// SetPid sets the pid of the command at the given index and writes the state file.
+func (f *StateFile) SetPid(idx, pid int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if idx >= len(f.state.Commands) {
+		return nil
+	}
+	f.state.Commands[idx].Pid = pid
+	return f.write()
+}
+
+// Write writes the state file.
+func (f *StateFile) Write() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.write()
+}
+
+// write replaces the state file at once, so that it is never read half
+// written. Has to be called with f.mu held.
+func (f *StateFile) write() error {
+	data, err := json.MarshalIndent(f.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// Remove removes the state file once concur is done.
+func (f *StateFile) Remove() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return os.Remove(f.path)
+}
@@ -4,62 +4,51 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"os/exec"
 	"time"
 )
 
 type CommandHealthChecker struct {
-	command  string
-	interval time.Duration
-
-	messages []string
-	lastRows int
+	*runner
+	command string
+	env     []string
+	assert  Assertions
 }
 
-func NewCommandHealthChecker(command string, interval time.Duration) *CommandHealthChecker {
-	return &CommandHealthChecker{
-		command:  command,
-		interval: interval,
+// NewCommandHealthChecker creates a check that runs the command, which passes
+// with the expected exit code and, if set, output matching assert.Body.
+func NewCommandHealthChecker(command string, interval time.Duration, env []string, assert Assertions, thresholds Thresholds) *CommandHealthChecker {
+	c := &CommandHealthChecker{
+		command: command,
+		env:     env,
+		assert:  assert,
 	}
+	// A command runs until it exits, without a timeout.
+	c.runner = newRunner(c.probe, interval, 0, thresholds)
+	return c
 }
 
-func (c *CommandHealthChecker) GetHealthCheckMessage(context.Context) (messageRows []string, rows int) {
-	newMessages := make([]string, len(c.messages))
-	copy(newMessages, c.messages)
-	lastRows := c.lastRows
-	c.lastRows = len(newMessages)
-	return newMessages, lastRows
-}
-
-func (c *CommandHealthChecker) Start(ctx context.Context) {
-	ticker := time.NewTicker(2 * time.Millisecond)
-	first := true
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			if first {
-				first = false
-				ticker = time.NewTicker(c.interval)
-			}
-			c.runCommand(ctx)
-		}
-	}
-}
-
-func (c *CommandHealthChecker) runCommand(ctx context.Context) error {
+func (c *CommandHealthChecker) probe(ctx context.Context) ([]string, error) {
 	var buf bytes.Buffer
 
 	cmd := exec.CommandContext(ctx, "sh", "-c", c.command)
+	cmd.Env = c.env
 	cmd.Stdout = &buf
 	cmd.Stderr = &buf
 
-	if err := cmd.Start(); err != nil {
-		return err
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == c.assert.ExitCode {
+		// The expected exit code is no failure.
+		err = nil
+	} else if err == nil && c.assert.ExitCode != 0 {
+		err = fmt.Errorf("exit status 0, expected %d", c.assert.ExitCode)
+	}
+	if err == nil && c.assert.Body != nil && !c.assert.Body.MatchString(buf.String()) {
+		err = fmt.Errorf("output does not match %s", c.assert.Body)
 	}
-
-	err := cmd.Wait()
 
 	if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
 		buf.WriteByte('\n')
@@ -71,10 +60,7 @@ func (c *CommandHealthChecker) runCommand(ctx context.Context) error {
 	var messages []string
 	scanner := bufio.NewScanner(&buf)
 	for scanner.Scan() {
-		text := scanner.Text()
-		messages = append(messages, text)
+		messages = append(messages, scanner.Text())
 	}
-
-	c.messages = messages
-	return scanner.Err()
+	return messages, err
 }
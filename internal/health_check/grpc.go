@@ -0,0 +1,104 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"text/template"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const defaultGRPCTemplate = `{{.Address}}{{with .Service}}/{{.}}{{end}} -> {{if .Error}}{{.Error}}{{else}}{{.Status}} ({{.Latency}}){{end}}`
+
+// GRPCHealthChecker calls the standard grpc.health.v1.Health/Check RPC and
+// is healthy while the service is SERVING.
+type GRPCHealthChecker struct {
+	*runner
+	address  string
+	service  string
+	metadata metadata.MD
+	conn     *grpc.ClientConn
+	client   healthpb.HealthClient
+	template *template.Template
+}
+
+type GRPCHealthCheckData struct {
+	Address string
+	Service string
+	// Status is the serving status, like SERVING or NOT_SERVING.
+	Status  string
+	Latency time.Duration
+	Error   string
+}
+
+// NewGRPCHealthChecker creates a check of the service at the address, which
+// is checked over TLS if tlsConfig is not nil. The headers are sent as
+// metadata. The timeout defaults to half the interval.
+func NewGRPCHealthChecker(address, service string, headers map[string]string, tlsConfig *tls.Config, t string, interval, timeout time.Duration, thresholds Thresholds) (*GRPCHealthChecker, error) {
+	if t == "" {
+		t = defaultGRPCTemplate
+	}
+	template, err := template.New("grpc").Funcs(tmplFnMap).Parse(t)
+	if err != nil {
+		return nil, err
+	}
+	if timeout == 0 {
+		timeout = interval / 2
+	}
+
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	// The connection is only established by the first check.
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &GRPCHealthChecker{
+		address:  address,
+		service:  service,
+		metadata: metadata.New(headers),
+		conn:     conn,
+		client:   healthpb.NewHealthClient(conn),
+		template: template,
+	}
+	c.runner = newRunner(c.probe, interval, timeout, thresholds)
+	return c, nil
+}
+
+// Start runs the checks until the context is done and closes the connection.
+func (c *GRPCHealthChecker) Start(ctx context.Context) {
+	defer c.conn.Close()
+	c.runner.Start(ctx)
+}
+
+func (c *GRPCHealthChecker) probe(ctx context.Context) ([]string, error) {
+	ctx = metadata.NewOutgoingContext(ctx, c.metadata)
+
+	data := &GRPCHealthCheckData{
+		Address: c.address,
+		Service: c.service,
+	}
+	start := time.Now()
+	res, err := c.client.Check(ctx, &healthpb.HealthCheckRequest{Service: c.service})
+	data.Latency = time.Since(start).Round(time.Microsecond)
+	if err != nil {
+		s := status.Convert(err)
+		data.Error = s.Code().String() + ": " + s.Message()
+	} else {
+		data.Status = res.GetStatus().String()
+		if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			err = fmt.Errorf("service is %s", data.Status)
+		}
+	}
+	return renderRows(c.template, data), err
+}
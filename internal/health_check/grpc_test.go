@@ -0,0 +1,98 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthServer serves the standard health service, which blocks checks of
+// the service "slow" until the client gives up.
+func healthServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if r, ok := req.(*healthpb.HealthCheckRequest); ok && r.GetService() == "slow" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return handler(ctx, req)
+	}
+	s := grpc.NewServer(grpc.UnaryInterceptor(block))
+	h := health.NewServer()
+	h.SetServingStatus("api", healthpb.HealthCheckResponse_SERVING)
+	h.SetServingStatus("db", healthpb.HealthCheckResponse_NOT_SERVING)
+	h.SetServingStatus("slow", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, h)
+	go func() { _ = s.Serve(l) }()
+	t.Cleanup(s.Stop)
+	return l.Addr().String()
+}
+
+func TestGRPCHealthChecker(t *testing.T) {
+	address := healthServer(t)
+
+	tests := []struct {
+		name    string
+		service string
+		status  Status
+		message string
+	}{
+		{
+			name:    "serving",
+			service: "api",
+			status:  StatusHealthy,
+			message: "SERVING",
+		},
+		{
+			name:    "not serving",
+			service: "db",
+			status:  StatusUnhealthy,
+			message: "NOT_SERVING",
+		},
+		{
+			name:    "unknown service",
+			service: "cache",
+			status:  StatusUnhealthy,
+			message: "NotFound: unknown service",
+		},
+		{
+			name:    "timeout",
+			service: "slow",
+			status:  StatusUnhealthy,
+			message: "DeadlineExceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewGRPCHealthChecker(address, tt.service, nil, nil, "", time.Second, 200*time.Millisecond, Thresholds{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.conn.Close()
+
+			start := time.Now()
+			c.check(context.Background())
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("check took %s, longer than the timeout", elapsed)
+			}
+
+			if status := c.Status(); status != tt.status {
+				t.Errorf("status = %s, want %s", status, tt.status)
+			}
+			messages := c.Messages()
+			if len(messages) != 1 || !strings.Contains(messages[0], tt.message) {
+				t.Errorf("messages = %q, want a row containing %q", messages, tt.message)
+			}
+		})
+	}
+}
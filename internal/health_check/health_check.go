@@ -2,22 +2,74 @@ package healthcheck
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"regexp"
 
 	"github.com/akatranlp/concur/internal/config"
 )
 
 type HealthChecker interface {
 	Start(ctx context.Context)
+	// Status returns the status decided by the checks so far.
+	Status() Status
 	GetHealthCheckMessage(ctx context.Context) (messageRows []string, rows int)
+	// Messages returns the rows of the last check without marking them as
+	// printed, unlike GetHealthCheckMessage.
+	Messages() []string
+	// Healthy reports whether the status is healthy.
+	Healthy() bool
 }
 
-func HealthCheckFactory(cfg config.StatusCheckConfig) (HealthChecker, error) {
+// HealthCheckFactory creates the check of the config. Command checks run
+// with the environment env.
+func HealthCheckFactory(cfg config.StatusCheckConfig, env []string) (HealthChecker, error) {
+	thresholds := Thresholds{
+		Healthy:     cfg.HealthyThreshold,
+		Unhealthy:   cfg.UnhealthyThreshold,
+		StartPeriod: cfg.StartPeriod,
+	}
+	assert, err := newAssertions(cfg.Assert)
+	if err != nil {
+		return nil, err
+	}
+
 	switch cfg.Type {
 	case config.CheckTypeCommand:
-		return NewCommandHealthChecker(cfg.Command, cfg.Interval), nil
+		return NewCommandHealthChecker(cfg.Command, cfg.Interval, env, assert, thresholds), nil
 	case config.CheckTypeHTTP:
-		return NewHTTPHealthChecker(cfg.URL, cfg.Template, cfg.Interval)
+		tlsConfig, err := cfg.TLS.Config()
+		if err != nil {
+			return nil, err
+		}
+		return NewHTTPHealthChecker(cfg.URL, cfg.Method, cfg.Headers, cfg.Body, cfg.Redirects, tlsConfig, cfg.Template, cfg.Interval, cfg.Timeout, assert, thresholds)
+	case config.CheckTypeTCP:
+		return NewTCPHealthChecker(cfg.Address, cfg.Send, cfg.Expect, cfg.Template, cfg.Interval, cfg.Timeout, thresholds)
+	case config.CheckTypeGRPC:
+		var tlsConfig *tls.Config
+		if cfg.TLS.Enabled {
+			if tlsConfig, err = cfg.TLS.Config(); err != nil {
+				return nil, err
+			}
+		}
+		return NewGRPCHealthChecker(cfg.Address, cfg.Service, cfg.Headers, tlsConfig, cfg.Template, cfg.Interval, cfg.Timeout, thresholds)
 	}
 	return nil, fmt.Errorf("invalid check type: %s", cfg.Type)
 }
+
+func newAssertions(cfg config.AssertConfig) (Assertions, error) {
+	assert := Assertions{
+		StatusCodes: cfg.StatusCodes,
+		ExitCode:    cfg.ExitCode,
+	}
+	if cfg.Body != "" {
+		var err error
+		if assert.Body, err = regexp.Compile(cfg.Body); err != nil {
+			return Assertions{}, err
+		}
+	}
+	for _, json := range cfg.JSON {
+		assert.JSON = append(assert.JSON, JSONAssertion{Path: json.Path, Equals: json.Equals})
+	}
+	return assert, nil
+}
@@ -1,25 +1,29 @@
 package healthcheck
 
 import (
-	"bufio"
-	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strings"
 	"text/template"
 	"time"
+
+	"github.com/akatranlp/concur/internal/config"
 )
 
 type HTTPHealthChecker struct {
+	*runner
 	url      *url.URL
+	method   string
+	headers  map[string]string
+	body     string
+	client   *http.Client
+	assert   Assertions
 	template *template.Template
-	interval time.Duration
-
-	messages []string
-	lastRows int
 }
 
 type HTTPHealthCheckData struct {
@@ -27,8 +31,14 @@ type HTTPHealthCheckData struct {
 	StatusCode int
 	Error      string
 	Body       string
+	// Failure is the assertion that failed, if any.
+	Failure string
 }
 
+// maxBodySize is the number of bytes of a response that are read, so that a
+// large or endless response does not fill the memory.
+const maxBodySize = 1 << 20
+
 var bodyRegex = regexp.MustCompile(`{{.*\.Body.*}}`)
 var tmplFnMap = template.FuncMap{
 	"jsonParse": func(body string) map[string]interface{} {
@@ -38,11 +48,20 @@ var tmplFnMap = template.FuncMap{
 	},
 }
 
-func NewHTTPHealthChecker(u, t string, interval time.Duration) (*HTTPHealthChecker, error) {
+// NewHTTPHealthChecker creates a check that sends the request to the url.
+// The method defaults to GET and the timeout to half the interval. tlsConfig
+// is used for https urls.
+func NewHTTPHealthChecker(u, method string, headers map[string]string, body string, redirects config.RedirectPolicy, tlsConfig *tls.Config, t string, interval, timeout time.Duration, assert Assertions, thresholds Thresholds) (*HTTPHealthChecker, error) {
 	url, err := url.Parse(u)
 	if err != nil {
 		return nil, err
 	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	if _, err := http.NewRequest(method, url.String(), nil); err != nil {
+		return nil, err
+	}
 	template, err := template.New("http").Funcs(tmplFnMap).Parse(t)
 	if err != nil {
 		return nil, err
@@ -54,84 +73,78 @@ func NewHTTPHealthChecker(u, t string, interval time.Duration) (*HTTPHealthCheck
 		}
 	}
 
-	return &HTTPHealthChecker{
-		url:      url,
-		template: template,
-		interval: interval,
-	}, nil
-}
-
-func (c *HTTPHealthChecker) GetHealthCheckMessage(context.Context) (messageRows []string, rows int) {
-	newMessages := make([]string, len(c.messages))
-	copy(newMessages, c.messages)
-	lastRows := c.lastRows
-	c.lastRows = len(newMessages)
-	return newMessages, lastRows
-}
+	if timeout == 0 {
+		timeout = interval / 2
+	}
 
-func (c *HTTPHealthChecker) Start(ctx context.Context) {
-	ticker := time.NewTicker(2 * time.Millisecond)
-	first := true
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			if first {
-				first = false
-				ticker = time.NewTicker(c.interval)
-			}
-			c.runCommand(ctx)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	client := &http.Client{Transport: transport}
+	if redirects == config.RedirectPolicyNone {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
 		}
 	}
-}
 
-func (c *HTTPHealthChecker) runCommand(ctx context.Context) {
-	ctx, cancel := context.WithTimeout(ctx, c.interval/2)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, "GET", c.url.String(), nil)
-	if err != nil {
-		panic("unreachable")
+	c := &HTTPHealthChecker{
+		url:      url,
+		method:   method,
+		headers:  headers,
+		body:     body,
+		client:   client,
+		assert:   assert,
+		template: template,
 	}
+	c.runner = newRunner(c.probe, interval, timeout, thresholds)
+	return c, nil
+}
 
+func (c *HTTPHealthChecker) probe(ctx context.Context) ([]string, error) {
 	data := &HTTPHealthCheckData{
 		URL:        c.url.String(),
-		Error:      "",
-		Body:       "",
 		StatusCode: -1,
 	}
+	err := c.request(ctx, data)
+	if err != nil {
+		data.Error = err.Error()
+	} else if err = c.assert.checkStatusCode(data.StatusCode); err != nil {
+		data.Failure = err.Error()
+	} else if err = c.assert.checkBody(data.Body); err != nil {
+		data.Failure = err.Error()
+	}
+	return renderRows(c.template, data), err
+}
 
-	defer func() {
-		var messages []string
-		var buf bytes.Buffer
-		if err := c.template.Execute(&buf, data); err != nil {
-			panic(err)
-		}
-
-		scanner := bufio.NewScanner(&buf)
-		for scanner.Scan() {
-			text := scanner.Text()
-			messages = append(messages, text)
+// request sends the request and sets the status code and body of the
+// response.
+func (c *HTTPHealthChecker) request(ctx context.Context, data *HTTPHealthCheckData) error {
+	var reqBody io.Reader
+	if c.body != "" {
+		reqBody = strings.NewReader(c.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, c.method, c.url.String(), reqBody)
+	if err != nil {
+		return err
+	}
+	for key, value := range c.headers {
+		if strings.EqualFold(key, "host") {
+			req.Host = value
+			continue
 		}
+		req.Header.Set(key, value)
+	}
 
-		c.messages = messages
-	}()
-
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.client.Do(req)
 	if err != nil {
-		data.Error = err.Error()
-		return
+		return err
 	}
-	data.StatusCode = res.StatusCode
-
 	defer res.Body.Close()
+	data.StatusCode = res.StatusCode
 
-	body, err := io.ReadAll(res.Body)
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxBodySize))
 	if err != nil {
-		data.Error = err.Error()
-		return
+		return err
 	}
 	data.Body = string(body)
-	return
+	return nil
 }
@@ -0,0 +1,166 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akatranlp/concur/internal/config"
+)
+
+// request is what the test server received.
+type request struct {
+	method string
+	host   string
+	header http.Header
+	body   string
+}
+
+// tlsServer redirects /redirect to / and answers every other request with
+// "ok" and a large body on /large. The received requests are sent on the
+// returned channel.
+func tlsServer(t *testing.T) (*httptest.Server, <-chan request) {
+	t.Helper()
+	requests := make(chan request, 10)
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{method: r.Method, host: r.Host, header: r.Header, body: string(body)}
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/", http.StatusFound)
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat("x", 2*maxBodySize)))
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s, requests
+}
+
+// caFile writes the certificate of the server into a file, as the CA of a
+// TLSConfig.
+func caFile(t *testing.T, s *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHTTPHealthCheckerRequest(t *testing.T) {
+	s, requests := tlsServer(t)
+	tlsConfig, err := config.TLSConfig{CA: caFile(t, s)}.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := map[string]string{"Authorization": "Bearer token", "Host": "api.local"}
+	c, err := NewHTTPHealthChecker(s.URL+"/", http.MethodPost, headers, `{"ping":true}`, "", tlsConfig, "", time.Second, time.Second, Assertions{}, Thresholds{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data HTTPHealthCheckData
+	if err := c.request(context.Background(), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.StatusCode != http.StatusOK || data.Body != "ok" {
+		t.Errorf("response = %d %q, want 200 \"ok\"", data.StatusCode, data.Body)
+	}
+
+	r := <-requests
+	if r.method != http.MethodPost {
+		t.Errorf("method = %s, want POST", r.method)
+	}
+	if r.host != "api.local" {
+		t.Errorf("host = %s, want api.local", r.host)
+	}
+	if got := r.header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer token")
+	}
+	if r.body != `{"ping":true}` {
+		t.Errorf("body = %q, want %q", r.body, `{"ping":true}`)
+	}
+}
+
+func TestHTTPHealthCheckerTLS(t *testing.T) {
+	s, _ := tlsServer(t)
+
+	tests := []struct {
+		name    string
+		tls     config.TLSConfig
+		wantErr string
+	}{
+		{name: "unknown authority", wantErr: "certificate"},
+		{name: "custom CA", tls: config.TLSConfig{CA: caFile(t, s)}},
+		{name: "insecure", tls: config.TLSConfig{InsecureSkipVerify: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := tt.tls.Config()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := NewHTTPHealthChecker(s.URL, "", nil, "", "", tlsConfig, "", time.Second, time.Second, Assertions{}, Thresholds{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var data HTTPHealthCheckData
+			err = c.request(context.Background(), &data)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPHealthCheckerResponse(t *testing.T) {
+	s, _ := tlsServer(t)
+	tlsConfig := &tls.Config{RootCAs: s.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs}
+
+	tests := []struct {
+		name       string
+		path       string
+		redirects  config.RedirectPolicy
+		statusCode int
+		bodySize   int
+	}{
+		{name: "follow redirects", path: "/redirect", statusCode: http.StatusOK, bodySize: 2},
+		{name: "no redirects", path: "/redirect", redirects: config.RedirectPolicyNone, statusCode: http.StatusFound},
+		{name: "large body", path: "/large", statusCode: http.StatusOK, bodySize: maxBodySize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewHTTPHealthChecker(s.URL+tt.path, "", nil, "", tt.redirects, tlsConfig, "", time.Second, time.Second, Assertions{}, Thresholds{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var data HTTPHealthCheckData
+			if err := c.request(context.Background(), &data); err != nil {
+				t.Fatal(err)
+			}
+			if data.StatusCode != tt.statusCode {
+				t.Errorf("status code = %d, want %d", data.StatusCode, tt.statusCode)
+			}
+			if tt.bodySize > 0 && len(data.Body) != tt.bodySize {
+				t.Errorf("body size = %d, want %d", len(data.Body), tt.bodySize)
+			}
+		})
+	}
+}
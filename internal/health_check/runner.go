@@ -0,0 +1,102 @@
+package healthcheck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// probe runs a single check and returns the rows to show. A nil error means
+// the check passed.
+type probe func(ctx context.Context) (rows []string, err error)
+
+// runner runs a probe at an interval and keeps the rows of the last check
+// and the status decided by the checks. Every checker is built on it.
+type runner struct {
+	probe    probe
+	interval time.Duration
+	// timeout limits a single check, 0 only limits it by the context.
+	timeout time.Duration
+
+	mu       sync.Mutex
+	messages []string
+	lastRows int
+	healthState
+}
+
+func newRunner(p probe, interval, timeout time.Duration, thresholds Thresholds) *runner {
+	return &runner{
+		probe:       p,
+		interval:    interval,
+		timeout:     timeout,
+		healthState: healthState{thresholds: thresholds},
+	}
+}
+
+func (r *runner) Start(ctx context.Context) {
+	ticker := time.NewTicker(2 * time.Millisecond)
+	defer func() { ticker.Stop() }()
+	first := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if first {
+				first = false
+				ticker.Stop()
+				ticker = time.NewTicker(r.interval)
+			}
+			r.check(ctx)
+		}
+	}
+}
+
+func (r *runner) check(ctx context.Context) {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	rows, err := r.probe(ctx)
+
+	r.mu.Lock()
+	r.messages = rows
+	r.mu.Unlock()
+	r.record(err == nil)
+}
+
+func (r *runner) GetHealthCheckMessage(context.Context) (messageRows []string, rows int) {
+	newMessages := r.Messages()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lastRows := r.lastRows
+	r.lastRows = len(newMessages)
+	return newMessages, lastRows
+}
+
+func (r *runner) Messages() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	messages := make([]string, len(r.messages))
+	copy(messages, r.messages)
+	return messages
+}
+
+// renderRows executes the template and splits the result into rows. A
+// failing template shows its error.
+func renderRows(t *template.Template, data any) []string {
+	var rows []string
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		rows = append(rows, err.Error())
+	}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		rows = append(rows, scanner.Text())
+	}
+	return rows
+}
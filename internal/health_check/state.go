@@ -0,0 +1,171 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is the state of a health check.
+type Status string
+
+const (
+	StatusStarting  Status = "starting"
+	StatusHealthy   Status = "healthy"
+	StatusUnhealthy Status = "unhealthy"
+)
+
+// Thresholds decide when a check changes its status, like the HEALTHCHECK
+// of Docker. Zero thresholds default to 1.
+type Thresholds struct {
+	// Healthy is the number of passed checks in a row to become healthy.
+	Healthy int
+	// Unhealthy is the number of failed checks in a row to become unhealthy.
+	Unhealthy int
+	// StartPeriod is the time after the first check in which failed checks
+	// don't count, unless the check was healthy already.
+	StartPeriod time.Duration
+}
+
+// Assertions decide whether a single check passed.
+type Assertions struct {
+	// StatusCodes are the expected status codes of http checks, 2xx and
+	// 3xx if empty.
+	StatusCodes []int
+	// Body has to match the body of http checks or the output of command
+	// checks.
+	Body *regexp.Regexp
+	// JSON are the values the JSON body of http checks has to contain.
+	JSON []JSONAssertion
+	// ExitCode is the expected exit code of command checks.
+	ExitCode int
+}
+
+// JSONAssertion expects the value at a path like checks.db.status or
+// items.0.name to equal Equals.
+type JSONAssertion struct {
+	Path   string
+	Equals any
+}
+
+// checkStatusCode returns an error if the status code is not expected.
+func (a Assertions) checkStatusCode(code int) error {
+	if len(a.StatusCodes) == 0 {
+		if code < 200 || code >= 400 {
+			return fmt.Errorf("unexpected status code %d", code)
+		}
+		return nil
+	}
+	for _, expected := range a.StatusCodes {
+		if code == expected {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected status code %d", code)
+}
+
+// checkBody returns an error if the body does not match the regex or the
+// JSON assertions.
+func (a Assertions) checkBody(body string) error {
+	if a.Body != nil && !a.Body.MatchString(body) {
+		return fmt.Errorf("body does not match %s", a.Body)
+	}
+	if len(a.JSON) == 0 {
+		return nil
+	}
+
+	var v any
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return fmt.Errorf("body is no JSON: %w", err)
+	}
+	for _, assertion := range a.JSON {
+		value, ok := lookupJSON(v, assertion.Path)
+		if !ok {
+			return fmt.Errorf("%s not found", assertion.Path)
+		}
+		if fmt.Sprint(value) != fmt.Sprint(assertion.Equals) {
+			return fmt.Errorf("%s is %v, not %v", assertion.Path, value, assertion.Equals)
+		}
+	}
+	return nil
+}
+
+// lookupJSON returns the value at the dot separated path, numbers index arrays.
+func lookupJSON(v any, path string) (any, bool) {
+	for _, key := range strings.Split(strings.TrimPrefix(path, "$."), ".") {
+		switch value := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = value[key]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(value) {
+				return nil, false
+			}
+			v = value[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// healthState keeps track of the status of a check from the results of the
+// single checks.
+type healthState struct {
+	thresholds Thresholds
+
+	mu       sync.Mutex
+	status   Status
+	passes   int
+	failures int
+	started  time.Time
+}
+
+// Status returns the status decided by the checks so far.
+func (s *healthState) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status == "" {
+		return StatusStarting
+	}
+	return s.status
+}
+
+// Healthy reports whether the check is healthy.
+func (s *healthState) Healthy() bool {
+	return s.Status() == StatusHealthy
+}
+
+// record counts the result of a single check.
+func (s *healthState) record(passed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started.IsZero() {
+		s.started = time.Now()
+	}
+
+	if passed {
+		s.failures = 0
+		s.passes++
+		if s.passes >= max(s.thresholds.Healthy, 1) {
+			s.status = StatusHealthy
+		}
+		return
+	}
+
+	s.passes = 0
+	if s.status == "" && time.Since(s.started) < s.thresholds.StartPeriod {
+		return
+	}
+	s.failures++
+	if s.failures >= max(s.thresholds.Unhealthy, 1) {
+		s.status = StatusUnhealthy
+	}
+}
@@ -0,0 +1,196 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthStateRecord(t *testing.T) {
+	const (
+		starting  = StatusStarting
+		healthy   = StatusHealthy
+		unhealthy = StatusUnhealthy
+	)
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		// startedAgo moves the first check into the past.
+		startedAgo time.Duration
+		results    []bool
+		// statuses are the statuses after each result.
+		statuses []Status
+	}{
+		{
+			name:     "default thresholds",
+			results:  []bool{true, false, true},
+			statuses: []Status{healthy, unhealthy, healthy},
+		},
+		{
+			name:       "healthy threshold",
+			thresholds: Thresholds{Healthy: 3},
+			results:    []bool{true, true, false, true, true, true},
+			statuses:   []Status{starting, starting, unhealthy, unhealthy, unhealthy, healthy},
+		},
+		{
+			name:       "unhealthy threshold",
+			thresholds: Thresholds{Unhealthy: 2},
+			results:    []bool{true, false, true, false, false},
+			statuses:   []Status{healthy, healthy, healthy, healthy, unhealthy},
+		},
+		{
+			name:       "failures during the start period",
+			thresholds: Thresholds{StartPeriod: time.Hour},
+			results:    []bool{false, false, false, true},
+			statuses:   []Status{starting, starting, starting, healthy},
+		},
+		{
+			name:       "healthy during the start period",
+			thresholds: Thresholds{StartPeriod: time.Hour},
+			results:    []bool{true, false},
+			statuses:   []Status{healthy, unhealthy},
+		},
+		{
+			name:       "failures after the start period",
+			thresholds: Thresholds{StartPeriod: time.Second},
+			startedAgo: 2 * time.Second,
+			results:    []bool{false},
+			statuses:   []Status{unhealthy},
+		},
+		{
+			name:       "healthy to unhealthy after the start period",
+			thresholds: Thresholds{Unhealthy: 2, StartPeriod: time.Second},
+			startedAgo: 2 * time.Second,
+			results:    []bool{true, false, false},
+			statuses:   []Status{healthy, healthy, unhealthy},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &healthState{thresholds: tt.thresholds}
+			if tt.startedAgo > 0 {
+				s.started = time.Now().Add(-tt.startedAgo)
+			}
+			if status := s.Status(); status != starting {
+				t.Fatalf("initial status = %s, want %s", status, starting)
+			}
+
+			var statuses []Status
+			for _, passed := range tt.results {
+				s.record(passed)
+				statuses = append(statuses, s.Status())
+			}
+			if !slices.Equal(statuses, tt.statuses) {
+				t.Errorf("statuses = %v, want %v", statuses, tt.statuses)
+			}
+		})
+	}
+}
+
+func TestLookupJSON(t *testing.T) {
+	var v any
+	body := `{"status":"up","checks":{"db":{"status":"down"}},"items":[{"name":"a"},{"name":"b"}],"count":2}`
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		value any
+		ok    bool
+	}{
+		{path: "status", value: "up", ok: true},
+		{path: "$.status", value: "up", ok: true},
+		{path: "checks.db.status", value: "down", ok: true},
+		{path: "$.checks.db.status", value: "down", ok: true},
+		{path: "items.1.name", value: "b", ok: true},
+		{path: "$.items.0.name", value: "a", ok: true},
+		{path: "count", value: float64(2), ok: true},
+		{path: "missing"},
+		{path: "checks.cache.status"},
+		{path: "items.2.name"},
+		{path: "items.-1.name"},
+		{path: "items.first.name"},
+		{path: "status.value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			value, ok := lookupJSON(v, tt.path)
+			if ok != tt.ok || value != tt.value {
+				t.Errorf("lookupJSON = %v, %t, want %v, %t", value, ok, tt.value, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	tests := []struct {
+		name    string
+		assert  Assertions
+		code    int
+		body    string
+		wantErr string
+	}{
+		{name: "default 2xx", code: 204},
+		{name: "default 3xx", code: 302},
+		{name: "default 4xx", code: 404, wantErr: "unexpected status code 404"},
+		{name: "default 1xx", code: 101, wantErr: "unexpected status code 101"},
+		{name: "expected code", assert: Assertions{StatusCodes: []int{200, 503}}, code: 503},
+		{name: "unexpected code", assert: Assertions{StatusCodes: []int{204}}, code: 200, wantErr: "unexpected status code 200"},
+		{name: "body matches", assert: Assertions{Body: regexp.MustCompile(`^ok`)}, code: 200, body: "ok\n"},
+		{name: "body mismatch", assert: Assertions{Body: regexp.MustCompile(`^ok`)}, code: 200, body: "error", wantErr: "body does not match ^ok"},
+		{
+			name:   "json matches",
+			assert: Assertions{JSON: []JSONAssertion{{Path: "$.status", Equals: "up"}, {Path: "count", Equals: 2}}},
+			code:   200,
+			body:   `{"status":"up","count":2}`,
+		},
+		{
+			name:    "json mismatch",
+			assert:  Assertions{JSON: []JSONAssertion{{Path: "status", Equals: "up"}}},
+			code:    200,
+			body:    `{"status":"down"}`,
+			wantErr: "status is down, not up",
+		},
+		{
+			name:    "json missing",
+			assert:  Assertions{JSON: []JSONAssertion{{Path: "status", Equals: "up"}}},
+			code:    200,
+			body:    `{}`,
+			wantErr: "status not found",
+		},
+		{
+			name:    "no json",
+			assert:  Assertions{JSON: []JSONAssertion{{Path: "status", Equals: "up"}}},
+			code:    200,
+			body:    "<html>up</html>",
+			wantErr: "body is no JSON",
+		},
+		{
+			// The body is only parsed for JSON assertions.
+			name: "no json without json assertions",
+			code: 200,
+			body: "<html>up</html>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.assert.checkStatusCode(tt.code)
+			if err == nil {
+				err = tt.assert.checkBody(tt.body)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"text/template"
+	"time"
+)
+
+const defaultTCPTemplate = `{{.Address}} -> {{if .Error}}{{.Error}}{{else}}up ({{.Latency}}){{end}}`
+
+// maxBannerSize is the number of bytes read while waiting for the expected response.
+const maxBannerSize = 4096
+
+type TCPHealthChecker struct {
+	*runner
+	address  string
+	send     string
+	expect   string
+	template *template.Template
+}
+
+type TCPHealthCheckData struct {
+	Address string
+	Latency time.Duration
+	Error   string
+	// Response is what was read while waiting for the expected response.
+	Response string
+}
+
+// NewTCPHealthChecker creates a check that connects to the address, sends
+// send if set and waits for expect if set. The timeout covers all of it
+// and defaults to half the interval.
+func NewTCPHealthChecker(address, send, expect, t string, interval, timeout time.Duration, thresholds Thresholds) (*TCPHealthChecker, error) {
+	if t == "" {
+		t = defaultTCPTemplate
+	}
+	template, err := template.New("tcp").Funcs(tmplFnMap).Parse(t)
+	if err != nil {
+		return nil, err
+	}
+	if timeout == 0 {
+		timeout = interval / 2
+	}
+
+	c := &TCPHealthChecker{
+		address:  address,
+		send:     send,
+		expect:   expect,
+		template: template,
+	}
+	c.runner = newRunner(c.probe, interval, timeout, thresholds)
+	return c, nil
+}
+
+func (c *TCPHealthChecker) probe(ctx context.Context) ([]string, error) {
+	data := &TCPHealthCheckData{Address: c.address}
+	start := time.Now()
+	response, err := c.dial(ctx)
+	data.Latency = time.Since(start).Round(time.Microsecond)
+	data.Response = response
+	if err != nil {
+		data.Error = err.Error()
+	}
+	return renderRows(c.template, data), err
+}
+
+// dial connects to the address and exchanges the configured bytes. It
+// returns what was read.
+func (c *TCPHealthChecker) dial(ctx context.Context) (string, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if c.send != "" {
+		if _, err := conn.Write([]byte(c.send)); err != nil {
+			return "", err
+		}
+	}
+	if c.expect == "" {
+		return "", nil
+	}
+
+	var response []byte
+	buf := make([]byte, 512)
+	for len(response) < maxBannerSize {
+		n, err := conn.Read(buf)
+		response = append(response, buf[:n]...)
+		if bytes.Contains(response, []byte(c.expect)) {
+			return string(response), nil
+		}
+		if err != nil {
+			return string(response), fmt.Errorf("expected response not received: %w", err)
+		}
+	}
+	return string(response), errors.New("expected response not received")
+}
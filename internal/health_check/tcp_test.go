@@ -0,0 +1,99 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoServer answers the first read of every connection with the same bytes
+// and closes the connection.
+func echoServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 512)
+				n, err := conn.Read(buf)
+				if err != nil {
+					return
+				}
+				_, _ = conn.Write(buf[:n])
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestTCPHealthChecker(t *testing.T) {
+	address := echoServer(t)
+
+	tests := []struct {
+		name    string
+		send    string
+		expect  string
+		status  Status
+		message string
+	}{
+		{
+			name:    "connect",
+			status:  StatusHealthy,
+			message: address + " -> up",
+		},
+		{
+			name:    "match",
+			send:    "PING\r\n",
+			expect:  "PING",
+			status:  StatusHealthy,
+			message: address + " -> up",
+		},
+		{
+			name:    "mismatch",
+			send:    "PING\r\n",
+			expect:  "PONG",
+			status:  StatusUnhealthy,
+			message: "expected response not received: EOF",
+		},
+		{
+			// Nothing is echoed without sending something first.
+			name:    "expect timeout",
+			expect:  "PONG",
+			status:  StatusUnhealthy,
+			message: "i/o timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewTCPHealthChecker(address, tt.send, tt.expect, "", time.Second, 200*time.Millisecond, Thresholds{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			c.check(context.Background())
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("check took %s, longer than the timeout", elapsed)
+			}
+
+			if status := c.Status(); status != tt.status {
+				t.Errorf("status = %s, want %s", status, tt.status)
+			}
+			messages := c.Messages()
+			if len(messages) != 1 || !strings.Contains(messages[0], tt.message) {
+				t.Errorf("messages = %q, want a row containing %q", messages, tt.message)
+			}
+		})
+	}
+}
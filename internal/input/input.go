@@ -0,0 +1,89 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/akatranlp/concur/internal/cmd"
+	"github.com/akatranlp/concur/internal/logger"
+	"github.com/akatranlp/concur/internal/prefix"
+)
+
+// Handler forwards lines of input to the commands. A line starting with
+// "<name|index>:" goes to that command, every other line to the default one.
+// The commands have to be started with input enabled.
+type Handler struct {
+	prefix        *prefix.Prefix
+	commands      []*cmd.Command
+	defaultTarget int
+
+	mu      sync.Mutex
+	msgCh   chan<- logger.Message
+	stopped bool
+}
+
+func NewHandler(p *prefix.Prefix, commands []*cmd.Command, defaultTarget string, msgCh chan<- logger.Message) (*Handler, error) {
+	idx, ok := p.Lookup(defaultTarget)
+	if !ok {
+		return nil, fmt.Errorf("unknown input target: %s", defaultTarget)
+	}
+	return &Handler{
+		prefix:        p,
+		commands:      commands,
+		defaultTarget: idx,
+		msgCh:         msgCh,
+	}, nil
+}
+
+// Run reads the input line by line until it is closed.
+func (h *Handler) Run(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			h.forward(line)
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// Stop stops forwarding input, so that the message channel can be closed.
+func (h *Handler) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stopped = true
+}
+
+// forward writes the line to its target. The write happens without holding
+// the lock, so that a command which does not read its input cannot block
+// Stop.
+func (h *Handler) forward(line string) {
+	h.mu.Lock()
+	stopped := h.stopped
+	h.mu.Unlock()
+	if stopped {
+		return
+	}
+
+	idx := h.defaultTarget
+	if target, rest, ok := strings.Cut(line, ":"); ok {
+		if i, ok := h.prefix.Lookup(target); ok {
+			idx, line = i, rest
+		}
+	}
+
+	if _, err := h.commands[idx].WriteInput([]byte(line)); err != nil {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if !h.stopped {
+			h.msgCh <- logger.Message{ID: idx, Text: fmt.Sprintf("input not forwarded: %s\n", err)}
+		}
+	}
+}
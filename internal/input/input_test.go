@@ -0,0 +1,67 @@
+package input
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akatranlp/concur/internal/cmd"
+	"github.com/akatranlp/concur/internal/config"
+	"github.com/akatranlp/concur/internal/logger"
+	"github.com/akatranlp/concur/internal/prefix"
+)
+
+func TestStopWithBlockedInput(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The command never reads its input, so the pipe fills up.
+	c := cmd.NewCommand(ctx, config.RunCommandConfig{Command: "sleep 1000"})
+	c.EnableInput()
+	if _, err := c.StartWithPrefix(); err != nil {
+		t.Fatal(err)
+	}
+	msgCh := make(chan logger.Message, 10)
+	go func() { _ = c.WaitWithPrefix(0, msgCh) }()
+	go func() {
+		for range msgCh {
+		}
+	}()
+
+	p, err := prefix.NewPrefix(config.PrefixConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Add("sleep", "sleep 1000", 0, nil)
+	h, err := NewHandler(p, []*cmd.Command{c}, "0", msgCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, w := io.Pipe()
+	defer w.Close()
+	go func() { _ = h.Run(r) }()
+	line := strings.Repeat("x", 1<<16) + "\n"
+	go func() {
+		for range 4 {
+			if _, err := w.Write([]byte(line)); err != nil {
+				return
+			}
+		}
+	}()
+	// Gives the handler time to block on the full pipe.
+	time.Sleep(200 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		h.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop blocked on a command that does not read its input")
+	}
+}
@@ -0,0 +1,199 @@
+package keyboard
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/akatranlp/concur/internal/cmd"
+	"github.com/akatranlp/concur/internal/logger"
+	"golang.org/x/term"
+)
+
+const help = "keys: r<index> restart • s<index> stop • l list • c clear • q quit • ? help"
+
+// Controller controls the commands with single key presses in prefix mode.
+// "r" or "s" followed by the index of a command restarts or stops it, "l"
+// lists the commands, "c" clears the screen and "q" shuts everything down.
+type Controller struct {
+	commands []*cmd.Command
+	cancel   context.CancelFunc
+	out      io.Writer
+
+	// action is the pending "r" or "s" waiting for the index in digits.
+	action byte
+	digits string
+
+	mu      sync.Mutex
+	msgCh   chan<- logger.Message
+	restore func() error
+	stopped bool
+}
+
+// NewController creates the controller for the given commands. cancel is
+// called to shut the commands down when "q" is pressed.
+func NewController(commands []*cmd.Command, cancel context.CancelFunc, msgCh chan<- logger.Message) *Controller {
+	return &Controller{
+		commands: commands,
+		cancel:   cancel,
+		out:      RawOutput(os.Stderr),
+		msgCh:    msgCh,
+	}
+}
+
+// Run puts the terminal into raw mode and handles the pressed keys until the
+// input is closed. In raw mode Ctrl+C is read as a key instead of sending a
+// signal and the output needs RawOutput.
+func (k *Controller) Run(in *os.File) error {
+	k.mu.Lock()
+	if k.stopped {
+		k.mu.Unlock()
+		return nil
+	}
+	fd := int(in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		k.mu.Unlock()
+		return err
+	}
+	k.restore = func() error { return term.Restore(fd, state) }
+	k.mu.Unlock()
+	fmt.Fprintln(k.out, help)
+
+	reader := bufio.NewReader(in)
+	for {
+		key, err := reader.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		k.handle(key)
+	}
+}
+
+// Stop stops handling keys and restores the terminal, so that the message
+// channel can be closed.
+func (k *Controller) Stop() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.stopped = true
+	if k.restore != nil {
+		_ = k.restore()
+		k.restore = nil
+	}
+}
+
+func (k *Controller) handle(key byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.stopped {
+		return
+	}
+
+	if k.action != 0 {
+		k.handleIndex(key)
+		return
+	}
+
+	switch key {
+	case 'r', 's':
+		k.action = key
+	case 'l':
+		k.list()
+	case 'c':
+		// The output clears the screen itself, so that it can redraw what
+		// it keeps at the bottom.
+		k.msgCh <- logger.Message{Event: &logger.Event{Type: logger.EventClear}}
+	case 'q', 3: // 3 is Ctrl+C, which sends no signal in raw mode
+		fmt.Fprintln(k.out, "shutting down")
+		k.cancel()
+	case '?', 'h':
+		fmt.Fprintln(k.out, help)
+	}
+}
+
+// handleIndex collects the digits of the index for the pending action. The
+// action is run as soon as no other command could be meant, or on Enter.
+func (k *Controller) handleIndex(key byte) {
+	switch {
+	case key >= '0' && key <= '9':
+		k.digits += string(key)
+		idx, _ := strconv.Atoi(k.digits)
+		if idx == 0 || idx*10 >= len(k.commands) {
+			k.run()
+		}
+	case key == '\r' || key == '\n':
+		if k.digits == "" {
+			k.reset()
+			return
+		}
+		k.run()
+	default:
+		// Escape or any other key cancels the action.
+		k.reset()
+	}
+}
+
+func (k *Controller) run() {
+	action, digits := k.action, k.digits
+	k.reset()
+
+	idx, _ := strconv.Atoi(digits)
+	if idx >= len(k.commands) {
+		fmt.Fprintf(k.out, "no command with index %d\n", idx)
+		return
+	}
+
+	var err error
+	if action == 'r' {
+		err = k.commands[idx].Restart()
+	} else {
+		err = k.commands[idx].Stop()
+	}
+	if err != nil {
+		k.msgCh <- logger.Message{ID: idx, Text: fmt.Sprintf("%s\n", err)}
+	}
+}
+
+func (k *Controller) reset() {
+	k.action = 0
+	k.digits = ""
+}
+
+// RawOutput returns f, which translates "\n" to "\r\n" if it is a terminal,
+// because a terminal in raw mode only moves to the next line.
+func RawOutput(f *os.File) io.Writer {
+	if !term.IsTerminal(int(f.Fd())) {
+		return f
+	}
+	return crlfWriter{f}
+}
+
+type crlfWriter struct {
+	w io.Writer
+}
+
+func (w crlfWriter) Write(p []byte) (int, error) {
+	if _, err := w.w.Write(bytes.ReplaceAll(p, []byte("\n"), []byte("\r\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// list writes the state and pid of every command with its prefix.
+func (k *Controller) list() {
+	for i, c := range k.commands {
+		text := string(c.State())
+		if pid := c.Pid(); pid != 0 {
+			text += fmt.Sprintf(" (pid %d)", pid)
+		}
+		k.msgCh <- logger.Message{ID: i, Text: text + "\n"}
+	}
+}
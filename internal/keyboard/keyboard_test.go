@@ -0,0 +1,42 @@
+package keyboard
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/akatranlp/concur/internal/logger"
+)
+
+func TestCrlfWriter(t *testing.T) {
+	var b strings.Builder
+	n, err := crlfWriter{&b}.Write([]byte("one\ntwo\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len("one\ntwo\n") {
+		t.Errorf("n = %d, want the length of the input", n)
+	}
+	if got, want := b.String(), "one\r\ntwo\r\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestClearGoesThroughTheOutput(t *testing.T) {
+	var b strings.Builder
+	msgCh := make(chan logger.Message, 1)
+	k := NewController(nil, func() {}, msgCh)
+	k.out = &b
+
+	k.handle('c')
+	select {
+	case msg := <-msgCh:
+		if msg.Event == nil || msg.Event.Type != logger.EventClear {
+			t.Errorf("message = %+v, want a clear event", msg)
+		}
+	default:
+		t.Error("no clear event was sent")
+	}
+	if b.Len() > 0 {
+		t.Errorf("the controller wrote %q itself", b.String())
+	}
+}
@@ -0,0 +1,10 @@
+package logger
+
+import "regexp"
+
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)`)
+
+// StripANSI removes ANSI escape sequences like colors from the text.
+func StripANSI(text string) string {
+	return ansiRegex.ReplaceAllString(text, "")
+}
@@ -0,0 +1,234 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akatranlp/concur/internal/config"
+)
+
+const rotateTimeFormat = "2006-01-02T15-04-05.000"
+
+// LogFile writes the output of a command to a file, which is rotated once it
+// reaches its max size or age. Rotated segments are named like the file plus
+// the time of the rotation and are optionally compressed with gzip.
+type LogFile struct {
+	path     string
+	cfg      config.LogConfig
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	wg       sync.WaitGroup
+}
+
+func OpenLogFile(path string, cfg config.LogConfig) (*LogFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f := &LogFile{path: path, cfg: cfg}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *LogFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	if f.size > 0 {
+		// The age of a file written by an earlier run counts as well.
+		f.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// WriteLine writes a line of output with its uncolored prefix, according to
+// the prefix and stripAnsi options.
+func (f *LogFile) WriteLine(prefix, text string) error {
+	if !f.cfg.Prefix {
+		prefix = ""
+	}
+	line := prefix + text
+	if f.cfg.StripANSI {
+		line = StripANSI(line)
+	}
+	_, err := io.WriteString(f, line)
+	return err
+}
+
+func (f *LogFile) Write(p []byte) (int, error) {
+	if f.shouldRotate(len(p)) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *LogFile) shouldRotate(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.cfg.MaxSize > 0 && f.size+int64(n) > int64(f.cfg.MaxSize) {
+		return true
+	}
+	return f.cfg.MaxAge > 0 && time.Since(f.openedAt) > f.cfg.MaxAge
+}
+
+func (f *LogFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	segment := f.segmentName()
+	if err := os.Rename(f.path, segment); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	// Compressing and removing old segments happens in the background, one
+	// rotation after the other.
+	f.wg.Wait()
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		if f.cfg.Compress {
+			_ = compressFile(segment)
+		}
+		f.removeOldSegments()
+	}()
+	return nil
+}
+
+// segmentName returns an unused name for the next rotated segment, which
+// contains the time of the rotation.
+func (f *LogFile) segmentName() string {
+	name := f.path + "." + time.Now().Format(rotateTimeFormat)
+	segment := name
+	for i := 1; exists(segment) || exists(segment+".gz"); i++ {
+		segment = fmt.Sprintf("%s-%d", name, i)
+	}
+	return segment
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// removeOldSegments removes the oldest segments beyond maxBackups.
+func (f *LogFile) removeOldSegments() {
+	if f.cfg.MaxBackups == 0 {
+		return
+	}
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return
+	}
+
+	type segment struct {
+		name string
+		time time.Time
+		n    int
+	}
+	var segments []segment
+	for _, entry := range entries {
+		if t, n, ok := f.parseSegment(entry.Name()); ok {
+			segments = append(segments, segment{entry.Name(), t, n})
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		if !segments[i].time.Equal(segments[j].time) {
+			return segments[i].time.Before(segments[j].time)
+		}
+		return segments[i].n < segments[j].n
+	})
+	for len(segments) > f.cfg.MaxBackups {
+		_ = os.Remove(filepath.Join(filepath.Dir(f.path), segments[0].name))
+		segments = segments[1:]
+	}
+}
+
+// parseSegment returns the rotation time and the counter of a segment name
+// as written by segmentName. Other files, like api.log.bak next to api.log,
+// are no segments.
+func (f *LogFile) parseSegment(name string) (time.Time, int, bool) {
+	rest, ok := strings.CutPrefix(name, filepath.Base(f.path)+".")
+	if !ok || len(rest) < len(rotateTimeFormat) {
+		return time.Time{}, 0, false
+	}
+	t, err := time.ParseInLocation(rotateTimeFormat, rest[:len(rotateTimeFormat)], time.Local)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+
+	rest = strings.TrimSuffix(rest[len(rotateTimeFormat):], ".gz")
+	if rest == "" {
+		return t, 0, true
+	}
+	counter, ok := strings.CutPrefix(rest, "-")
+	if !ok {
+		return time.Time{}, 0, false
+	}
+	n, err := strconv.Atoi(counter)
+	if err != nil || n < 1 {
+		return time.Time{}, 0, false
+	}
+	return t, n, true
+}
+
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Close closes the file and waits for a running compression.
+func (f *LogFile) Close() error {
+	err := f.file.Close()
+	f.wg.Wait()
+	return err
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/akatranlp/concur/internal/config"
+)
+
+func TestRemoveOldSegmentsKeepsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.log")
+	names := []string{
+		"api.log.2024-01-01T10-00-00.000.gz",
+		"api.log.2024-01-01T11-00-00.000",
+		"api.log.2024-01-01T11-00-00.000-1",
+		"api.log.2024-01-01T12-00-00.000",
+		// The log of a command named api.log and files of the user.
+		"api.log.log",
+		"api.log.bak",
+		"api.log.2024-01-01T09-00-00.000.bak",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := &LogFile{path: path, cfg: config.LogConfig{MaxBackups: 2}}
+	f.removeOldSegments()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	want := []string{
+		"api.log.2024-01-01T09-00-00.000.bak",
+		"api.log.2024-01-01T11-00-00.000-1",
+		"api.log.2024-01-01T12-00-00.000",
+		"api.log.bak",
+		"api.log.log",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("files = %q, want %q", got, want)
+	}
+}
+
+func TestOpenLogFileKeepsAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenLogFile(path, config.LogConfig{MaxAge: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if !f.shouldRotate(1) {
+		t.Error("a file older than maxAge is not rotated after reopening it")
+	}
+}
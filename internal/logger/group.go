@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/akatranlp/concur/internal/config"
+)
+
+// SetGroupOrder enables group mode, in which the output of every command is
+// buffered and printed as one block once the command is done.
+func (l *PrefixLogger) SetGroupOrder(order config.GroupOrder) {
+	if order == "" {
+		order = config.GroupOrderConfig
+	}
+	l.group = order
+	l.blocks = make(map[int]*strings.Builder)
+	l.completed = make(map[int]bool)
+}
+
+// buffer adds the message to the block of its command and prints the blocks
+// that are complete according to the group order.
+func (l *PrefixLogger) buffer(ctx context.Context, done bool, msg Message) {
+	if msg.Text != "" {
+		block, ok := l.blocks[msg.ID]
+		if !ok {
+			block = &strings.Builder{}
+			l.blocks[msg.ID] = block
+		}
+		block.WriteString(l.render(msg))
+	}
+	if msg.Event == nil || msg.Event.Type != EventDone {
+		return
+	}
+
+	l.completed[msg.ID] = true
+	if l.group == config.GroupOrderCompletion {
+		l.flushBlock(ctx, done, msg.ID)
+		return
+	}
+	for l.completed[l.nextBlock] {
+		l.flushBlock(ctx, done, l.nextBlock)
+		l.nextBlock++
+	}
+}
+
+// flushGroups prints the remaining blocks in config order.
+func (l *PrefixLogger) flushGroups(ctx context.Context, done bool) {
+	ids := make([]int, 0, len(l.blocks))
+	for id := range l.blocks {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		l.flushBlock(ctx, done, id)
+	}
+}
+
+func (l *PrefixLogger) flushBlock(ctx context.Context, done bool, id int) {
+	block, ok := l.blocks[id]
+	if !ok {
+		return
+	}
+	delete(l.blocks, id)
+	l.writeOutput(ctx, done, block.String())
+}
@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	hc "github.com/akatranlp/concur/internal/health_check"
+)
+
+// Record is a line of the JSON output. Output lines of a command have the
+// event "output", messages of concur itself have the event "message".
+type Record struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	Index      *int      `json:"index,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Command    string    `json:"command,omitempty"`
+	Pid        int       `json:"pid,omitempty"`
+	Stream     Stream    `json:"stream,omitempty"`
+	Text       string    `json:"text,omitempty"`
+	Code       *int      `json:"code,omitempty"`
+	Signal     string    `json:"signal,omitempty"`
+	DurationMs *int64    `json:"durationMs,omitempty"`
+	Check      *int      `json:"check,omitempty"`
+	Healthy    *bool     `json:"healthy,omitempty"`
+	Status     hc.Status `json:"status,omitempty"`
+}
+
+func (l *PrefixLogger) writeRecord(msg Message) {
+	data := l.prefix.Data(msg.ID)
+	record := Record{
+		Time:    time.Now(),
+		Event:   "output",
+		Index:   &msg.ID,
+		Name:    data.Name,
+		Command: data.Command,
+		Pid:     data.Pid,
+		Stream:  msg.Stream,
+		Text:    strings.TrimSuffix(msg.Text, "\n"),
+	}
+	if msg.Stream == "" {
+		record.Event = "message"
+	}
+	if event := msg.Event; event != nil {
+		record.Event = string(event.Type)
+		if event.Pid != 0 {
+			record.Pid = event.Pid
+		}
+		if event.Type == EventExit {
+			duration := event.Duration.Milliseconds()
+			record.Code = &event.Code
+			record.Signal = event.Signal
+			record.DurationMs = &duration
+		}
+	}
+	l.encode(record)
+}
+
+// writeHealthRecords writes a record for every health check whose state
+// changed since the last call.
+func (l *PrefixLogger) writeHealthRecords(ctx context.Context) {
+	if l.health == nil {
+		l.health = make(map[hc.HealthChecker]hc.Status)
+	}
+	for i, checker := range l.healthCheckers {
+		if record, ok := l.healthRecord(ctx, checker); ok {
+			record.Check = &i
+			l.encode(record)
+		}
+	}
+	for i, checker := range l.commandChecks {
+		if checker == nil {
+			continue
+		}
+		if record, ok := l.healthRecord(ctx, checker); ok {
+			data := l.prefix.Data(i)
+			record.Index = &i
+			record.Name = data.Name
+			record.Command = data.Command
+			record.Pid = data.Pid
+			l.encode(record)
+		}
+	}
+}
+
+func (l *PrefixLogger) healthRecord(ctx context.Context, checker hc.HealthChecker) (Record, bool) {
+	status := checker.Status()
+	if last, ok := l.health[checker]; ok && last == status {
+		return Record{}, false
+	}
+	l.health[checker] = status
+	healthy := status == hc.StatusHealthy
+
+	messages, _ := checker.GetHealthCheckMessage(ctx)
+	return Record{
+		Time:    time.Now(),
+		Event:   "health",
+		Healthy: &healthy,
+		Status:  status,
+		Text:    StripANSI(strings.Join(messages, "\n")),
+	}, true
+}
+
+func (l *PrefixLogger) encode(record Record) {
+	enc := json.NewEncoder(l.out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(record); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to write record:", err)
+	}
+}
@@ -3,7 +3,9 @@ package logger
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/akatranlp/concur/internal/config"
@@ -11,14 +13,62 @@ import (
 	"github.com/akatranlp/concur/internal/prefix"
 )
 
+// Stream is the output stream a message was written to by a command.
+type Stream string
+
+const (
+	StreamStdout Stream = "stdout"
+	StreamStderr Stream = "stderr"
+)
+
+// EventType is the type of a lifecycle event of a command.
+type EventType string
+
+const (
+	EventStart EventType = "start"
+	EventReady EventType = "ready"
+	EventExit  EventType = "exit"
+	// EventDone is sent once the command is not restarted anymore.
+	EventDone EventType = "done"
+	// EventClear asks the output to clear the screen, it belongs to no
+	// command.
+	EventClear EventType = "clear"
+)
+
+// Event is a lifecycle event of a command. Code, Signal and Duration are only
+// set for EventExit.
+type Event struct {
+	Type     EventType
+	Pid      int
+	Code     int
+	Signal   string
+	Duration time.Duration
+}
+
+// Message is a line of output of a command. Messages of concur itself, like
+// the exit status of a command, have no stream and may carry an event.
+// Messages without text are not shown in the text output.
 type Message struct {
-	ID   int
-	Text string
+	ID     int
+	Text   string
+	Stream Stream
+	Event  *Event
 }
 
 type PrefixLogger struct {
-	prefix *prefix.Prefix
-	out    *os.File
+	prefix     *prefix.Prefix
+	out        io.Writer
+	stderrOnly bool
+	logFiles   []*LogFile
+
+	json          bool
+	commandChecks []hc.HealthChecker
+	health        map[hc.HealthChecker]hc.Status
+
+	group     config.GroupOrder
+	blocks    map[int]*strings.Builder
+	completed map[int]bool
+	nextBlock int
 
 	healthCheckers      []hc.HealthChecker
 	healthCheckInterval time.Duration
@@ -27,7 +77,7 @@ type PrefixLogger struct {
 	msgCh               chan Message
 }
 
-func NewPrefixLogger(p *prefix.Prefix, output *os.File, healthCheckers []hc.HealthChecker, cfg config.StatusConfig) *PrefixLogger {
+func NewPrefixLogger(p *prefix.Prefix, output io.Writer, healthCheckers []hc.HealthChecker, cfg config.StatusConfig, stderrOnly bool) *PrefixLogger {
 	var healthCheckerPrefix string
 	if cfg.Enabled {
 		healthCheckerPrefix = cfg.Sequence.Apply("["+cfg.Text+"]") + " "
@@ -35,6 +85,7 @@ func NewPrefixLogger(p *prefix.Prefix, output *os.File, healthCheckers []hc.Heal
 	return &PrefixLogger{
 		prefix:              p,
 		out:                 output,
+		stderrOnly:          stderrOnly,
 		healthCheckers:      healthCheckers,
 		healthCheckInterval: 1,
 		healthCheckerPrefix: healthCheckerPrefix,
@@ -47,12 +98,30 @@ func (l *PrefixLogger) GetMessageChannel() chan<- Message {
 	return l.msgCh
 }
 
+// SetLogFiles sets the files the output of the commands is also written to,
+// indexed by the command. The files are closed when the logger is done.
+func (l *PrefixLogger) SetLogFiles(files []*LogFile) {
+	l.logFiles = files
+}
+
+// SetOutputFormat sets whether the output is written as text or as JSON Lines.
+func (l *PrefixLogger) SetOutputFormat(format config.OutputFormat) {
+	l.json = format == config.OutputFormatJSON
+}
+
+// SetCommandHealthCheckers sets the health checks of the commands, indexed by
+// the command, whose updates are written in the JSON output.
+func (l *PrefixLogger) SetCommandHealthCheckers(checkers []hc.HealthChecker) {
+	l.commandChecks = checkers
+}
+
 func (l *PrefixLogger) Close() {
 	close(l.msgCh)
 }
 
 func (l *PrefixLogger) Run(ctx context.Context) {
 	defer close(l.done)
+	defer l.closeLogFiles()
 	ticker := time.NewTicker(l.healthCheckInterval * time.Second)
 
 	var done bool
@@ -66,8 +135,12 @@ func (l *PrefixLogger) Run(ctx context.Context) {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
 		select {
 		case <-ticker.C:
+			if l.json {
+				l.writeHealthRecords(ctx)
+				break
+			}
 			if done || len(l.healthCheckers) == 0 {
-				continue
+				break
 			}
 
 			healthMessages := make([]string, 0)
@@ -75,51 +148,138 @@ func (l *PrefixLogger) Run(ctx context.Context) {
 
 			for _, hc := range l.healthCheckers {
 				message, oldRows := hc.GetHealthCheckMessage(ctx)
-				healthMessages = append(healthMessages, message...)
+				healthMessages = append(healthMessages, colorHealth(hc.Status(), message)...)
 				oldHelthMessageRows += oldRows
 			}
 
 			if oldHelthMessageRows > 0 {
-				l.out.WriteString(fmt.Sprintf("\033[%dA\033[0J", oldHelthMessageRows))
+				fmt.Fprintf(l.out, "\033[%dA\033[0J", oldHelthMessageRows)
 			}
 			l.RenderHealthCheck(healthMessages)
 		case msg, ok := <-l.msgCh:
 			if !ok {
+				l.flushGroups(ctx, done)
 				cancel()
 				return
 			}
+			if msg.Event != nil && msg.Event.Type == EventClear {
+				if !l.json {
+					l.clear(ctx, done)
+				}
+				break
+			}
 
-			prefix := l.prefix.Render(msg.ID, true)
+			l.writeLogFile(msg)
+			if l.stderrOnly && msg.Stream == StreamStdout {
+				break
+			}
+			if l.json {
+				l.writeRecord(msg)
+				break
+			}
+			if l.group != "" {
+				l.buffer(ctx, done, msg)
+				break
+			}
+			if msg.Text == "" {
+				break
+			}
+			l.writeOutput(ctx, done, l.render(msg))
+		}
+		cancel()
+	}
+}
 
-			if !done && len(l.healthCheckers) > 0 {
-				healthMessages := make([]string, 0)
-				oldHelthMessageRows := 0
+// render returns the prefixed text of a message.
+func (l *PrefixLogger) render(msg Message) string {
+	prefix := l.prefix.RenderStream(msg.ID, string(msg.Stream), true)
+	if msg.Stream != "" && config.ColorsEnabled() {
+		// Resets the colors the command did not reset itself.
+		return prefix + msg.Text + "\033[0m"
+	}
+	return prefix + msg.Text
+}
 
-				for _, hc := range l.healthCheckers {
-					message, oldRows := hc.GetHealthCheckMessage(ctx)
-					healthMessages = append(healthMessages, message...)
-					oldHelthMessageRows += oldRows
-				}
-				if oldHelthMessageRows > 0 {
-					l.out.WriteString(fmt.Sprintf("\033[%dA\033[0J", oldHelthMessageRows))
-				}
+// writeOutput writes the text above the rendered health checks.
+func (l *PrefixLogger) writeOutput(ctx context.Context, done bool, text string) {
+	if !done && len(l.healthCheckers) > 0 {
+		healthMessages := make([]string, 0)
+		oldHelthMessageRows := 0
 
-				l.out.WriteString(prefix)
-				l.out.WriteString(msg.Text)
-				l.RenderHealthCheck(healthMessages)
+		for _, hc := range l.healthCheckers {
+			message, oldRows := hc.GetHealthCheckMessage(ctx)
+			healthMessages = append(healthMessages, colorHealth(hc.Status(), message)...)
+			oldHelthMessageRows += oldRows
+		}
+		if oldHelthMessageRows > 0 {
+			fmt.Fprintf(l.out, "\033[%dA\033[0J", oldHelthMessageRows)
+		}
 
-			} else {
-				l.out.WriteString(prefix)
-				l.out.WriteString(msg.Text)
-			}
+		io.WriteString(l.out, text)
+		l.RenderHealthCheck(healthMessages)
+
+	} else {
+		io.WriteString(l.out, text)
+	}
+}
+
+// clear clears the screen and draws the health checks again at the top, so
+// that the next redraw only removes their rows.
+func (l *PrefixLogger) clear(ctx context.Context, done bool) {
+	io.WriteString(l.out, "\033[2J\033[H")
+	if done || len(l.healthCheckers) == 0 {
+		return
+	}
+	healthMessages := make([]string, 0)
+	for _, hc := range l.healthCheckers {
+		message, _ := hc.GetHealthCheckMessage(ctx)
+		healthMessages = append(healthMessages, colorHealth(hc.Status(), message)...)
+	}
+	l.RenderHealthCheck(healthMessages)
+}
+
+func (l *PrefixLogger) writeLogFile(msg Message) {
+	if msg.ID >= len(l.logFiles) || l.logFiles[msg.ID] == nil {
+		return
+	}
+	prefix := l.prefix.RenderStream(msg.ID, string(msg.Stream), false)
+	if err := l.logFiles[msg.ID].WriteLine(prefix, msg.Text); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to write log file:", err)
+		l.logFiles[msg.ID] = nil
+	}
+}
+
+func (l *PrefixLogger) closeLogFiles() {
+	for _, f := range l.logFiles {
+		if f != nil {
+			_ = f.Close()
 		}
-		cancel()
 	}
 }
 
+// healthColors are the colors of the rows of a health check by its status.
+var healthColors = map[hc.Status]string{
+	hc.StatusStarting:  "\033[33m",
+	hc.StatusHealthy:   "\033[32m",
+	hc.StatusUnhealthy: "\033[31m",
+}
+
+// colorHealth colors the rows of a health check by its status. Colors of the
+// template itself take precedence.
+func colorHealth(status hc.Status, rows []string) []string {
+	for i, row := range rows {
+		rows[i] = healthColors[status] + row
+	}
+	return rows
+}
+
 func (l *PrefixLogger) RenderHealthCheck(rows []string) {
 	for _, message := range rows {
-		l.out.WriteString(fmt.Sprintf("%s%s\033[0m\n", l.healthCheckerPrefix, message))
+		if !config.ColorsEnabled() {
+			fmt.Fprintf(l.out, "%s%s\n", l.healthCheckerPrefix, StripANSI(message))
+			continue
+		}
+		fmt.Fprintf(l.out, "%s%s\033[0m\n", l.healthCheckerPrefix, message)
 	}
 }
 
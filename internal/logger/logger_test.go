@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/akatranlp/concur/internal/config"
+	hc "github.com/akatranlp/concur/internal/health_check"
+	"github.com/akatranlp/concur/internal/prefix"
+)
+
+// fakeChecker always has the same rows and counts the printed rows like the
+// real checks do.
+type fakeChecker struct {
+	mu       sync.Mutex
+	rows     []string
+	lastRows int
+}
+
+func (c *fakeChecker) Start(context.Context) {}
+
+func (c *fakeChecker) Status() hc.Status { return hc.StatusHealthy }
+
+func (c *fakeChecker) Healthy() bool { return true }
+
+func (c *fakeChecker) Messages() []string { return append([]string(nil), c.rows...) }
+
+func (c *fakeChecker) GetHealthCheckMessage(context.Context) ([]string, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lastRows := c.lastRows
+	c.lastRows = len(c.rows)
+	return c.Messages(), lastRows
+}
+
+func TestPrefixLoggerClear(t *testing.T) {
+	config.SetColorsEnabled(false)
+	t.Cleanup(func() { config.SetColorsEnabled(true) })
+
+	p, err := prefix.NewPrefix(config.PrefixConfig{Template: "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Add("api", "./api", 0, nil)
+
+	var out strings.Builder
+	checker := &fakeChecker{rows: []string{"up", "ready"}}
+	l := NewPrefixLogger(p, &out, []hc.HealthChecker{checker}, config.StatusConfig{}, false)
+	go l.Run(context.Background())
+
+	msgCh := l.GetMessageChannel()
+	msgCh <- Message{ID: 0, Text: "one\n"}
+	msgCh <- Message{Event: &Event{Type: EventClear}}
+	msgCh <- Message{ID: 0, Text: "two\n"}
+	close(msgCh)
+	l.Wait()
+
+	// Only the rows drawn after clearing the screen are removed again.
+	want := "[api] one\nup\nready\n" +
+		"\033[2J\033[H" + "up\nready\n" +
+		"\033[2A\033[0J" + "[api] two\nup\nready\n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
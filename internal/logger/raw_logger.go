@@ -33,10 +33,10 @@ func NewRawLogger(healthCheckers []hc.HealthChecker, cfg config.StatusConfig) *R
 }
 
 func (l *RawLogger) Run(ctx context.Context) {
+	defer close(l.done)
 	if len(l.healthCheckers) == 0 {
 		return
 	}
-	defer close(l.done)
 	ticker := time.NewTicker(l.healthCheckInterval * time.Second)
 
 	for {
@@ -48,7 +48,7 @@ func (l *RawLogger) Run(ctx context.Context) {
 
 			for _, hc := range l.healthCheckers {
 				message, _ := hc.GetHealthCheckMessage(ctx)
-				healthMessages = append(healthMessages, message...)
+				healthMessages = append(healthMessages, colorHealth(hc.Status(), message)...)
 			}
 
 			l.RenderHealthCheck(healthMessages)
@@ -58,6 +58,10 @@ func (l *RawLogger) Run(ctx context.Context) {
 
 func (l *RawLogger) RenderHealthCheck(rows []string) {
 	for _, message := range rows {
+		if !config.ColorsEnabled() {
+			os.Stdout.WriteString(fmt.Sprintf("%s%s\n", l.healthCheckerPrefix, StripANSI(message)))
+			continue
+		}
 		os.Stdout.WriteString(fmt.Sprintf("%s%s\033[0m\n", l.healthCheckerPrefix, message))
 	}
 }
@@ -0,0 +1,25 @@
+package logger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/akatranlp/concur/internal/config"
+)
+
+func TestRawLoggerWaitWithoutHealthCheckers(t *testing.T) {
+	l := NewRawLogger(nil, config.StatusConfig{})
+	go l.Run(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		l.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return without health checkers")
+	}
+}
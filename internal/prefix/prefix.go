@@ -6,10 +6,12 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
 	"github.com/akatranlp/concur/internal/config"
+	"github.com/charmbracelet/x/ansi"
 )
 
 var templateRegex = regexp.MustCompile(`\{\{.*\}\}`)
@@ -18,13 +20,18 @@ var timeRegex = regexp.MustCompile(`\{\{.*\.Time.*\}\}`)
 var timeSinceStart = time.Now()
 
 type Prefix struct {
+	mu               sync.Mutex
 	template         *template.Template
 	input            string
 	maxCommandLength int
 	timeFormat       string
 	timesince        bool
 	isTimed          bool
-	data             []*PrefixData
+	stderr           config.StderrConfig
+	// padded is set by ApplyEvenPadding, so that stdout prefixes make room
+	// for the stderr marker.
+	padded bool
+	data   []*PrefixData
 }
 
 type PrefixData struct {
@@ -34,8 +41,9 @@ type PrefixData struct {
 	Command  string
 	Pid      int
 	Time     string
+	Stream   string
 	Padding  string
-	cache    string
+	cache    map[string]string
 }
 
 func NewPrefix(cfg config.PrefixConfig) (*Prefix, error) {
@@ -44,6 +52,7 @@ func NewPrefix(cfg config.PrefixConfig) (*Prefix, error) {
 		timesince:        cfg.TimeSinceStart,
 		maxCommandLength: cfg.PrefixLength,
 		input:            cfg.Template,
+		stderr:           cfg.Stderr,
 	}
 
 	input := cfg.Template
@@ -74,6 +83,9 @@ func NewPrefix(cfg config.PrefixConfig) (*Prefix, error) {
 }
 
 func (p *Prefix) Add(name, command string, pid int, seq *config.Sequence) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	idx := len(p.data)
 	p.data = append(p.data, &PrefixData{
 		Index:    idx,
@@ -85,15 +97,65 @@ func (p *Prefix) Add(name, command string, pid int, seq *config.Sequence) int {
 	return idx
 }
 
+// Lookup returns the index of the command the target refers to, either by
+// its index or by its name.
+func (p *Prefix) Lookup(target string) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, data := range p.data {
+		if config.MatchesCommand(target, data.Index, data.Name) {
+			return data.Index, true
+		}
+	}
+	return -1, false
+}
+
+// Data returns a copy of the data of the command at the given index.
+func (p *Prefix) Data(idx int) PrefixData {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if idx < 0 || idx >= len(p.data) {
+		panic("invalid index")
+	}
+	return *p.data[idx]
+}
+
+// SetPid updates the pid of an already added command, e.g. after a restart.
+func (p *Prefix) SetPid(idx, pid int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if idx < 0 || idx >= len(p.data) {
+		panic("invalid index")
+	}
+	p.data[idx].Pid = pid
+	p.data[idx].cache = nil
+}
+
 func (p *Prefix) Render(idx int, withColor bool) string {
+	return p.RenderStream(idx, "stdout", withColor)
+}
+
+// RenderStream renders the prefix of a line written to the given stream.
+// Lines without a stream are rendered like stdout lines.
+func (p *Prefix) RenderStream(idx int, stream string, withColor bool) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if idx < 0 || idx >= len(p.data) {
 		panic("invalid index")
 	}
+	if stream == "" {
+		stream = "stdout"
+	}
 
 	data := p.data[idx]
-	if data.cache != "" {
-		return data.cache
+	if cache, ok := data.cache[stream]; withColor && ok {
+		return cache
 	}
+	data.Stream = stream
 
 	var prefix string
 
@@ -149,14 +211,29 @@ func (p *Prefix) Render(idx int, withColor bool) string {
 		prefix = fmt.Sprintf("[%s%s]", prefix, data.Padding)
 	}
 
-	if data.sequence == nil || !withColor {
+	sequence := data.sequence
+	if stream == "stderr" {
+		if p.stderr.Sequence.IsSet() {
+			sequence = &p.stderr.Sequence
+		}
+		if p.stderr.Marker != "" {
+			prefix += " " + p.stderr.Marker
+		}
+	} else if p.padded && p.stderr.Marker != "" {
+		prefix += strings.Repeat(" ", 1+ansi.StringWidth(p.stderr.Marker))
+	}
+
+	if sequence == nil || !withColor {
 		prefix += " "
 	} else {
-		prefix = data.sequence.Apply(prefix) + " "
+		prefix = sequence.Apply(prefix) + " "
 	}
 
-	if !p.isTimed {
-		data.cache = prefix
+	if !p.isTimed && withColor {
+		if data.cache == nil {
+			data.cache = make(map[string]string)
+		}
+		data.cache[stream] = prefix
 	}
 	return prefix
 }
@@ -180,6 +257,7 @@ func (p *Prefix) ApplyEvenPadding() {
 		if padding > 0 {
 			data.Padding = fmt.Sprintf("%*s", padding, " ")
 		}
-		data.cache = ""
+		data.cache = nil
 	}
+	p.padded = true
 }
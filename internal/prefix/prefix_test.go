@@ -0,0 +1,60 @@
+package prefix
+
+import (
+	"testing"
+
+	"github.com/akatranlp/concur/internal/config"
+)
+
+func TestRenderStreamPadding(t *testing.T) {
+	tests := []struct {
+		name   string
+		marker string
+	}{
+		{name: "marker", marker: "!"},
+		{name: "wide marker", marker: "⚠ err"},
+		{name: "no marker"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPrefix(config.PrefixConfig{
+				Template: "name",
+				Stderr:   config.StderrConfig{Marker: tt.marker},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Add("api", "./api", 0, nil)
+			p.Add("database", "./db", 0, nil)
+			p.ApplyEvenPadding()
+
+			want := p.RenderStream(0, "stderr", false)
+			for i := range 2 {
+				for _, stream := range []string{"stdout", "stderr"} {
+					got := p.RenderStream(i, stream, false)
+					if len([]rune(got)) != len([]rune(want)) {
+						t.Errorf("%s prefix of %d = %q, not as wide as %q", stream, i, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRenderStreamWithoutPadding(t *testing.T) {
+	p, err := NewPrefix(config.PrefixConfig{
+		Template: "name",
+		Stderr:   config.StderrConfig{Marker: "!"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Add("api", "./api", 0, nil)
+
+	if got := p.RenderStream(0, "stdout", false); got != "[api] " {
+		t.Errorf("stdout prefix = %q, want %q", got, "[api] ")
+	}
+	if got := p.RenderStream(0, "stderr", false); got != "[api] ! " {
+		t.Errorf("stderr prefix = %q, want %q", got, "[api] ! ")
+	}
+}
@@ -0,0 +1,361 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akatranlp/concur/internal/cmd"
+	hc "github.com/akatranlp/concur/internal/health_check"
+	"github.com/akatranlp/concur/internal/logger"
+	"github.com/akatranlp/concur/internal/prefix"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// maxLines is the number of lines kept per command and in the combined view.
+	maxLines      = 5000
+	sidebarWidth  = 30
+	refreshPeriod = 200 * time.Millisecond
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	selectedStyle = lipgloss.NewStyle().Bold(true).Reverse(true)
+	dimStyle      = lipgloss.NewStyle().Faint(true)
+	sidebarStyle  = lipgloss.NewStyle().Width(sidebarWidth).BorderStyle(lipgloss.NormalBorder()).BorderRight(true).PaddingRight(1)
+
+	stateColors = map[cmd.State]lipgloss.Color{
+		cmd.StateWaiting:    lipgloss.Color("4"),
+		cmd.StateRunning:    lipgloss.Color("2"),
+		cmd.StateRestarting: lipgloss.Color("3"),
+		cmd.StateStopped:    lipgloss.Color("8"),
+		cmd.StateExited:     lipgloss.Color("8"),
+	}
+	healthColors = map[hc.Status]lipgloss.Color{
+		hc.StatusStarting:  lipgloss.Color("3"),
+		hc.StatusHealthy:   lipgloss.Color("2"),
+		hc.StatusUnhealthy: lipgloss.Color("1"),
+	}
+)
+
+type model struct {
+	prefix   *prefix.Prefix
+	commands []*cmd.Command
+	checks   []hc.HealthChecker
+	cancel   context.CancelFunc
+
+	logs      [][]line
+	all       []line
+	exitCodes []*int
+
+	// selected is 0 for the combined view, otherwise the command index + 1.
+	selected  int
+	viewport  viewport.Model
+	search    textinput.Model
+	searching bool
+	dirty     bool
+	width     int
+	height    int
+	notice    string
+	quitting  bool
+	closed    bool
+}
+
+func newModel(p *prefix.Prefix, commands []*cmd.Command, checks []hc.HealthChecker, cancel context.CancelFunc) *model {
+	search := textinput.New()
+	search.Prompt = "/"
+	return &model{
+		prefix:    p,
+		commands:  commands,
+		checks:    checks,
+		cancel:    cancel,
+		logs:      make([][]line, len(commands)),
+		exitCodes: make([]*int, len(commands)),
+		viewport:  viewport.New(0, 0),
+		search:    search,
+	}
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(refreshPeriod, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (m *model) Init() tea.Cmd {
+	return tick()
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.viewport.Width = max(m.width-sidebarWidth-2, 1)
+		m.viewport.Height = max(m.height-2, 1)
+		m.dirty = true
+		m.refresh()
+	case tickMsg:
+		m.refresh()
+		return m, tick()
+	case outputMsg:
+		m.add(logger.Message(msg))
+	case closedMsg:
+		// Keep the output of the commands readable until the user leaves.
+		m.closed = true
+		if m.quitting {
+			return m, tea.Quit
+		}
+		m.notice = "all commands are done, press q to leave"
+	case tea.MouseMsg:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	case tea.KeyMsg:
+		if m.searching {
+			return m, m.updateSearch(msg)
+		}
+		return m, m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m *model) add(msg logger.Message) {
+	if event := msg.Event; event != nil && event.Type == logger.EventExit {
+		code := event.Code
+		m.exitCodes[msg.ID] = &code
+	}
+	if msg.Text == "" || msg.ID < 0 || msg.ID >= len(m.logs) {
+		return
+	}
+
+	text := sanitize(msg.Text)
+	l := line{
+		id:       msg.ID,
+		text:     text,
+		prefixed: m.prefix.RenderStream(msg.ID, string(msg.Stream), true) + text,
+	}
+	m.logs[msg.ID] = appendLine(m.logs[msg.ID], l)
+	m.all = appendLine(m.all, l)
+	m.dirty = true
+}
+
+func appendLine(lines []line, l line) []line {
+	if len(lines) >= maxLines {
+		lines = lines[len(lines)-maxLines+1:]
+	}
+	return append(lines, l)
+}
+
+// refresh renders the lines of the selected view into the viewport and keeps
+// it at the bottom if it was there before.
+func (m *model) refresh() {
+	if !m.dirty || m.width == 0 {
+		return
+	}
+	m.dirty = false
+
+	follow := m.viewport.AtBottom()
+	lines := m.all
+	if m.selected > 0 {
+		lines = m.logs[m.selected-1]
+	}
+	query := strings.ToLower(m.search.Value())
+
+	var b strings.Builder
+	for _, l := range lines {
+		if query != "" && !strings.Contains(strings.ToLower(logger.StripANSI(l.text)), query) {
+			continue
+		}
+		text := l.text
+		if m.selected == 0 {
+			text = l.prefixed
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(ansi.Hardwrap(text, m.viewport.Width, true))
+		b.WriteString("\033[0m")
+	}
+	m.viewport.SetContent(b.String())
+	if follow {
+		m.viewport.GotoBottom()
+	}
+}
+
+func (m *model) handleKey(msg tea.KeyMsg) tea.Cmd {
+	m.notice = ""
+	switch key := msg.String(); key {
+	case "ctrl+c", "q":
+		if m.quitting || m.closed {
+			return tea.Quit
+		}
+		m.quitting = true
+		m.notice = "shutting down, press q again to leave now"
+		m.cancel()
+	case "up", "k", "shift+tab":
+		m.selectView((m.selected + len(m.commands)) % (len(m.commands) + 1))
+	case "down", "j", "tab":
+		m.selectView((m.selected + 1) % (len(m.commands) + 1))
+	case "a":
+		m.selectView(0)
+	case "r", "s":
+		if m.selected == 0 {
+			m.notice = "select a command first"
+			break
+		}
+		c := m.commands[m.selected-1]
+		var err error
+		if key == "r" {
+			err = c.Restart()
+		} else {
+			err = c.Stop()
+		}
+		if err != nil {
+			m.notice = err.Error()
+		}
+	case "/":
+		m.searching = true
+		return m.search.Focus()
+	case "esc":
+		m.search.SetValue("")
+		m.dirty = true
+		m.refresh()
+	case "pgup", "ctrl+u":
+		m.viewport.HalfViewUp()
+	case "pgdown", "ctrl+d":
+		m.viewport.HalfViewDown()
+	case "home", "g":
+		m.viewport.GotoTop()
+	case "end", "G":
+		m.viewport.GotoBottom()
+	default:
+		if idx, err := strconv.Atoi(key); err == nil && idx < len(m.commands) {
+			m.selectView(idx + 1)
+		}
+	}
+	return nil
+}
+
+func (m *model) updateSearch(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		m.searching = false
+		m.search.Blur()
+		return nil
+	case "esc":
+		m.searching = false
+		m.search.Blur()
+		m.search.SetValue("")
+		m.dirty = true
+		m.refresh()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	m.dirty = true
+	m.refresh()
+	return cmd
+}
+
+func (m *model) selectView(selected int) {
+	m.selected = selected
+	m.dirty = true
+	m.refresh()
+	m.viewport.GotoBottom()
+}
+
+func (m *model) View() string {
+	if m.width == 0 {
+		return ""
+	}
+	sidebar := sidebarStyle.Height(m.height - 1).Render(m.sidebarView())
+	main := lipgloss.JoinVertical(lipgloss.Left, m.headerView(), m.viewport.View())
+	return lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.JoinHorizontal(lipgloss.Top, sidebar, " ", main),
+		m.footerView(),
+	)
+}
+
+func (m *model) headerView() string {
+	title := "All commands"
+	if m.selected > 0 {
+		data := m.prefix.Data(m.selected - 1)
+		title = displayName(data)
+		if pid := m.commands[m.selected-1].Pid(); pid != 0 {
+			title += dimStyle.Render(fmt.Sprintf(" pid %d", pid))
+		}
+	}
+	if query := m.search.Value(); query != "" && !m.searching {
+		title += dimStyle.Render(fmt.Sprintf("  search: %s", query))
+	}
+	return ansi.Truncate(titleStyle.Render(title), m.viewport.Width, "…")
+}
+
+func (m *model) sidebarView() string {
+	var b strings.Builder
+	entry := func(selected bool, text string) {
+		text = ansi.Truncate(text, sidebarWidth-1, "…")
+		if selected {
+			text = selectedStyle.Render(text)
+		}
+		b.WriteString(text + "\n")
+	}
+
+	entry(m.selected == 0, "  All")
+	for i, c := range m.commands {
+		state := c.State()
+		dot := lipgloss.NewStyle().Foreground(stateColors[state]).Render("●")
+		status := string(state)
+		if code := m.exitCodes[i]; state == cmd.StateExited && code != nil {
+			status = fmt.Sprintf("exited %d", *code)
+			if *code != 0 {
+				dot = lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render("●")
+			}
+		}
+		if checker := c.HealthChecker(); checker != nil && state == cmd.StateRunning {
+			status = string(checker.Status())
+		}
+		name := displayName(m.prefix.Data(i))
+		entry(m.selected == i+1, fmt.Sprintf("%s %d %s %s", dot, i, name, dimStyle.Render(status)))
+	}
+
+	if len(m.checks) > 0 {
+		b.WriteString("\n" + titleStyle.Render("Status") + "\n")
+		for _, checker := range m.checks {
+			rows := checker.Messages()
+			dot := lipgloss.NewStyle().Foreground(healthColors[checker.Status()]).Render("●")
+			for j, row := range rows {
+				if j == 0 {
+					row = dot + " " + row
+				} else {
+					row = "  " + row
+				}
+				b.WriteString(ansi.Truncate(row, sidebarWidth-1, "…") + "\033[0m\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+func (m *model) footerView() string {
+	if m.searching {
+		return m.search.View()
+	}
+	if m.notice != "" {
+		return m.notice
+	}
+	help := "↑/↓ select • 0-9 focus • a all • r restart • s stop • / search • pgup/pgdown scroll • q quit"
+	return dimStyle.Render(ansi.Truncate(help, m.width, "…"))
+}
+
+func displayName(data prefix.PrefixData) string {
+	if data.Name != "" {
+		return data.Name
+	}
+	return data.Command
+}
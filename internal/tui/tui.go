@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/akatranlp/concur/internal/cmd"
+	hc "github.com/akatranlp/concur/internal/health_check"
+	"github.com/akatranlp/concur/internal/logger"
+	"github.com/akatranlp/concur/internal/prefix"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TUI shows the commands and their state in a sidebar and their output in a
+// scrollable pane. It takes the place of the PrefixLogger.
+type TUI struct {
+	model    *model
+	prefix   *prefix.Prefix
+	logFiles []*logger.LogFile
+	msgCh    chan logger.Message
+	done     chan struct{}
+}
+
+type outputMsg logger.Message
+
+type closedMsg struct{}
+
+type tickMsg time.Time
+
+// New creates the TUI for the given commands. cancel is called to shut the
+// commands down when the user quits.
+func New(p *prefix.Prefix, commands []*cmd.Command, healthCheckers []hc.HealthChecker, cancel context.CancelFunc) *TUI {
+	return &TUI{
+		model:  newModel(p, commands, healthCheckers, cancel),
+		prefix: p,
+		msgCh:  make(chan logger.Message, 100),
+		done:   make(chan struct{}),
+	}
+}
+
+func (t *TUI) GetMessageChannel() chan<- logger.Message {
+	return t.msgCh
+}
+
+// SetLogFiles sets the files the output of the commands is also written to,
+// indexed by the command. The files are closed when the TUI is done.
+func (t *TUI) SetLogFiles(files []*logger.LogFile) {
+	t.logFiles = files
+}
+
+func (t *TUI) Close() {
+	close(t.msgCh)
+}
+
+// Run shows the TUI until the message channel is closed. If the user quits
+// the TUI early, the remaining messages are still written to the log files.
+func (t *TUI) Run(context.Context) {
+	defer close(t.done)
+
+	program := tea.NewProgram(t.model, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for msg := range t.msgCh {
+			t.writeLogFile(msg)
+			// Send returns right away once the program exited.
+			program.Send(outputMsg(msg))
+		}
+		program.Send(closedMsg{})
+	}()
+
+	if _, err := program.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "tui:", err)
+	}
+	<-forwarded
+	for _, f := range t.logFiles {
+		if f != nil {
+			_ = f.Close()
+		}
+	}
+}
+
+func (t *TUI) writeLogFile(msg logger.Message) {
+	if msg.ID >= len(t.logFiles) || t.logFiles[msg.ID] == nil || msg.Text == "" {
+		return
+	}
+	prefix := t.prefix.RenderStream(msg.ID, string(msg.Stream), false)
+	if err := t.logFiles[msg.ID].WriteLine(prefix, msg.Text); err != nil {
+		t.logFiles[msg.ID] = nil
+	}
+}
+
+func (t *TUI) Wait() {
+	<-t.done
+}
+
+// line is a line of output of a command.
+type line struct {
+	id       int
+	text     string
+	prefixed string
+}
+
+func sanitize(text string) string {
+	text = strings.TrimSuffix(text, "\n")
+	// Only the last part of a line overwritten with carriage returns, like a
+	// progress bar, is visible in a terminal.
+	if i := strings.LastIndexByte(text, '\r'); i >= 0 {
+		text = text[i+1:]
+	}
+	return text
+}